@@ -1,10 +1,14 @@
 package main
 
 import (
+	"flag"
 	"forum/internal/controller"
+	"forum/internal/filestore"
+	"forum/internal/models"
 	"forum/internal/repository"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"forum/internal/service.go"
@@ -17,6 +21,13 @@ type Server struct {
 }
 
 func main() {
+	admin := flag.String("admin", "", "email of a registered user to promote to administrator")
+	commentDepth := flag.Int("comment-depth", 5, "number of reply levels indented below a comment")
+	uploads := flag.String("uploads", "uploads", "directory that keeps the files attached to posts")
+	reactions := flag.String("reactions", "🎉 ❤️ 🤔 🚀", "space separated emoji that users can react with besides liking and disliking")
+	reconcile := flag.Bool("reconcile", false, "recount the likes and dislikes of every post and comment, then exit")
+	flag.Parse()
+
 	db, err := repository.NewDB()
 	defer db.Close()
 	if err != nil {
@@ -28,9 +39,37 @@ func main() {
 		log.Fatal(err)
 	}
 
-	repos := repository.NewRepository(db)
-	services := service.NewService(repos)
-	handler := controller.NewHandler(services)
+	if search, err := repository.SearchAvailable(db); err != nil {
+		log.Fatal(err)
+	} else if !search {
+		log.Println("Search is disabled, build with -tags sqlite_fts5 to enable it")
+	}
+
+	if *reconcile {
+		fixed, err := repository.ReconcileReactions(db)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Recounted likes and dislikes, fixed the counters of %d posts and comments", fixed)
+		return
+	}
+
+	files, err := filestore.NewDisk(*uploads)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	repos := repository.NewRepository(db, files)
+	services := service.NewService(repos, service.Config{Reactions: strings.Fields(*reactions)})
+
+	if *admin != "" {
+		if err = services.SetUserRoleByEmail(*admin, models.RoleAdministrator); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("%s is now an administrator", *admin)
+	}
+
+	handler := controller.NewHandler(services, controller.Config{MaxCommentDepth: *commentDepth})
 
 	router := handler.InitRoutes()
 
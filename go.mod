@@ -3,8 +3,10 @@ module forum
 go 1.20
 
 require (
-	github.com/gofrs/uuid v4.4.0+incompatible // indirect
-	github.com/mattn/go-sqlite3 v1.14.17 // indirect
+	github.com/mattn/go-sqlite3 v1.14.17
 	github.com/satori/go.uuid v1.2.0
-	golang.org/x/crypto v0.14.0 // indirect
+	golang.org/x/crypto v0.14.0
+	golang.org/x/text v0.13.0
 )
+
+require github.com/gofrs/uuid v4.4.0+incompatible // indirect
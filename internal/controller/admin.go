@@ -0,0 +1,77 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// usersPage represents the data needed to render the user administration page.
+type usersPage struct {
+	User  models.User
+	Users []models.User
+	Roles []models.Role
+}
+
+// adminUsers lists every user together with their role.
+func (h *Handler) adminUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/admin-users.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	users, err := h.services.GetUsers()
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := &usersPage{
+		User:  r.Context().Value(ctxKeyUser).(models.User),
+		Users: users,
+		Roles: models.Roles,
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// setUserRole changes the role of a user.
+func (h *Handler) setUserRole(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	userID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	actor := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.SetUserRole(actor, userID, models.Role(r.FormValue("role"))); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRole):
+			h.errorPage(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, service.ErrForbidden):
+			h.errorPage(w, http.StatusForbidden, err.Error())
+		default:
+			h.errorPage(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
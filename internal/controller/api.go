@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"forum/internal/service.go"
+)
+
+// apiError is the body of every failed /api/v1 response.
+type apiError struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// apiErrorStatuses maps service errors to the HTTP status returned by the API.
+var apiErrorStatuses = []struct {
+	err    error
+	status int
+}{
+	{service.ErrInvalidPost, http.StatusBadRequest},
+	{service.ErrInvalidSort, http.StatusBadRequest},
+	{service.ErrInvalidCursor, http.StatusBadRequest},
+	{service.ErrInvalidFilter, http.StatusBadRequest},
+	{service.ErrInvalidTag, http.StatusBadRequest},
+	{service.ErrInvalidAttachment, http.StatusBadRequest},
+	{service.ErrInvalidComment, http.StatusBadRequest},
+	{service.ErrInvalidReaction, http.StatusBadRequest},
+	{service.ErrInvalidPage, http.StatusBadRequest},
+	{service.ErrInvalidEmail, http.StatusBadRequest},
+	{service.ErrInvalidUsername, http.StatusBadRequest},
+	{service.ErrInvalidPassword, http.StatusBadRequest},
+	{service.ErrUserExist, http.StatusConflict},
+	{service.ErrUsernameConfusable, http.StatusConflict},
+	{service.ErrForbidden, http.StatusForbidden},
+	{service.ErrUserNotFound, http.StatusNotFound},
+	{service.ErrSessionNotFound, http.StatusNotFound},
+	{service.ErrPostNotFound, http.StatusNotFound},
+	{service.ErrCommentNotFound, http.StatusNotFound},
+	{service.ErrAttachmentNotFound, http.StatusNotFound},
+	{service.ErrInvalidTokenName, http.StatusBadRequest},
+	{service.ErrInvalidScope, http.StatusBadRequest},
+	{service.ErrAccessTokenNotFound, http.StatusNotFound},
+	{service.ErrInvalidSearch, http.StatusBadRequest},
+	{service.ErrInvalidCategory, http.StatusBadRequest},
+	{service.ErrCategoryNotFound, http.StatusNotFound},
+	{service.ErrCategoryExists, http.StatusConflict},
+	{service.ErrSearchUnavailable, http.StatusServiceUnavailable},
+}
+
+// writeJSON encodes v as the JSON body of the response.
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("api: encode response: %v", err)
+	}
+}
+
+// apiErrorResponse writes a JSON error body with the given status.
+func (h *Handler) apiErrorResponse(w http.ResponseWriter, status int, msg string) {
+	log.Printf("%d - %s", status, msg)
+	h.writeJSON(w, status, apiError{Status: status, Error: msg})
+}
+
+// apiServiceError writes the JSON error body matching an error returned by the service layer.
+// Unknown errors are reported as 500 without exposing their details.
+func (h *Handler) apiServiceError(w http.ResponseWriter, err error) {
+	for _, e := range apiErrorStatuses {
+		if errors.Is(err, e.err) {
+			log.Printf("%d - %v", e.status, err)
+			h.writeJSON(w, e.status, apiError{Status: e.status, Error: e.err.Error()})
+			return
+		}
+	}
+
+	log.Printf("%d - %v", http.StatusInternalServerError, err)
+	h.writeJSON(w, http.StatusInternalServerError, apiError{
+		Status: http.StatusInternalServerError,
+		Error:  http.StatusText(http.StatusInternalServerError),
+	})
+}
+
+// apiMethodNotAllowed answers requests made with an unsupported method.
+func (h *Handler) apiMethodNotAllowed(w http.ResponseWriter) {
+	h.apiErrorResponse(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+}
+
+// apiNotFound answers requests for unknown API resources.
+func (h *Handler) apiNotFound(w http.ResponseWriter) {
+	h.apiErrorResponse(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+}
+
+// apiUnknown serves every /api/v1 path that has no handler of its own.
+func (h *Handler) apiUnknown(w http.ResponseWriter, r *http.Request) {
+	h.apiNotFound(w)
+}
+
+// apiAuthenticate is the JSON counterpart of authenticateUser combined with requireScope.
+func (h *Handler) apiAuthenticate(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, scopes, err := h.requestUser(r)
+		if err != nil {
+			h.apiErrorResponse(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+			return
+		}
+
+		if !service.HasScope(scopes, scope) {
+			h.apiErrorResponse(w, http.StatusForbidden, "access token is missing the "+scope+" scope")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user, scopes)))
+	}
+}
+
+// decodeJSON reads the JSON request body into v.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// pathSegments splits the part of the URL path that follows prefix into its segments.
+func pathSegments(path, prefix string) []string {
+	rest := strings.Trim(strings.TrimPrefix(path, prefix), "/")
+	if rest == "" {
+		return nil
+	}
+	return strings.Split(rest, "/")
+}
@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"net/http"
+	"time"
+)
+
+// apiCredentials is the request body of the sign-up and sign-in endpoints.
+type apiCredentials struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// apiSession is the response body of the sign-in endpoint.
+type apiSession struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// apiSignUp registers a new user.
+func (h *Handler) apiSignUp(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	var input apiCredentials
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	user := &models.User{
+		Username: input.Username,
+		Email:    input.Email,
+		Password: input.Password,
+	}
+
+	if err := h.services.Authorization.CreateUser(user); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, user)
+}
+
+// apiSignIn opens a new session and returns its token.
+// The token is also set as the session cookie, so either can be used for later requests.
+func (h *Handler) apiSignIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	var input apiCredentials
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	token, expiresAt, err := h.services.Authorization.GenerateSessionToken(input.Email, input.Password, r.UserAgent(), clientIP(r))
+	if err != nil {
+		h.apiErrorResponse(w, http.StatusUnauthorized, "invalid email or password")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    "sessionID",
+		Value:   token,
+		Path:    "/",
+		Expires: expiresAt,
+	})
+
+	h.writeJSON(w, http.StatusOK, apiSession{Token: token, ExpiresAt: expiresAt})
+}
+
+// apiLogOut closes the session used to make the request.
+func (h *Handler) apiLogOut(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.DeleteSessionToken(user.Token); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    "sessionID",
+		Value:   "",
+		Path:    "/",
+		Expires: time.Now(),
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiMe returns the signed-in user.
+func (h *Handler) apiMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, r.Context().Value(ctxKeyUser).(models.User))
+}
@@ -0,0 +1,249 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// apiCommentInput is the request body for creating a comment.
+type apiCommentInput struct {
+	Text     string `json:"text"`
+	ParentID int    `json:"parentId"`
+}
+
+// apiRevisionList is the response body of comment revision listings.
+type apiRevisionList struct {
+	Revisions []models.CommentRevision `json:"revisions"`
+}
+
+// apiComment serves /api/v1/comments/{id} and its sub-resources.
+func (h *Handler) apiComment(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, "/api/v1/comments/")
+	if len(segments) == 0 || len(segments) > 3 || len(segments) == 3 && segments[1] != "reactions" {
+		h.apiNotFound(w)
+		return
+	}
+
+	commentID, err := strconv.Atoi(segments[0])
+	if err != nil {
+		h.apiNotFound(w)
+		return
+	}
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			h.apiGetComment(w, commentID)
+		case http.MethodPut:
+			h.apiAuthenticate(service.ScopeComment, func(w http.ResponseWriter, r *http.Request) {
+				h.apiUpdateComment(w, r, commentID)
+			})(w, r)
+		case http.MethodDelete:
+			h.apiAuthenticate(service.ScopeComment, func(w http.ResponseWriter, r *http.Request) {
+				h.apiDeleteComment(w, r, commentID)
+			})(w, r)
+		default:
+			h.apiMethodNotAllowed(w)
+		}
+		return
+	}
+
+	switch segments[1] {
+	case "revisions":
+		if r.Method != http.MethodGet {
+			h.apiMethodNotAllowed(w)
+			return
+		}
+		h.apiAuthenticate(service.ScopeRead, func(w http.ResponseWriter, r *http.Request) {
+			h.apiCommentRevisions(w, r, commentID)
+		})(w, r)
+	case "like", "dislike":
+		if r.Method != http.MethodPost {
+			h.apiMethodNotAllowed(w)
+			return
+		}
+		reaction := segments[1]
+		h.apiAuthenticate(service.ScopeComment, func(w http.ResponseWriter, r *http.Request) {
+			h.apiReactToComment(w, r, commentID, reaction)
+		})(w, r)
+	case "reactions":
+		if len(segments) == 3 {
+			if r.Method != http.MethodGet {
+				h.apiMethodNotAllowed(w)
+				return
+			}
+			h.apiGetCommentReactionUsers(w, r, commentID, segments[2])
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.apiGetCommentReactions(w, h.apiViewer(r), commentID)
+		case http.MethodPost:
+			h.apiAuthenticate(service.ScopeComment, func(w http.ResponseWriter, r *http.Request) {
+				h.apiToggleCommentReaction(w, r, commentID)
+			})(w, r)
+		default:
+			h.apiMethodNotAllowed(w)
+		}
+	default:
+		h.apiNotFound(w)
+	}
+}
+
+// apiGetComments lists the comments of a post.
+func (h *Handler) apiGetComments(w http.ResponseWriter, postID int) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	comments, err := h.services.Comment.GetComments(postID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	if comments == nil {
+		comments = []*models.Comment{}
+	}
+
+	h.writeJSON(w, http.StatusOK, apiCommentList{Comments: comments})
+}
+
+// apiVisibleComment returns a comment that is neither hidden nor deleted, on a post that is not hidden,
+// or writes the not found error and reports false.
+func (h *Handler) apiVisibleComment(w http.ResponseWriter, commentID int) (models.Comment, bool) {
+	comment, err := h.services.Comment.GetCommentByID(commentID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return models.Comment{}, false
+	}
+
+	if comment.Hidden || comment.Deleted {
+		h.apiServiceError(w, service.ErrCommentNotFound)
+		return models.Comment{}, false
+	}
+
+	post, err := h.services.PostItem.GetPostByID(comment.PostID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return models.Comment{}, false
+	}
+
+	if post.Hidden {
+		h.apiServiceError(w, service.ErrCommentNotFound)
+		return models.Comment{}, false
+	}
+
+	return comment, true
+}
+
+// apiGetComment returns a single comment.
+func (h *Handler) apiGetComment(w http.ResponseWriter, commentID int) {
+	comment, ok := h.apiVisibleComment(w, commentID)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, comment)
+}
+
+// apiCreateComment adds a comment of the signed-in user to a post.
+func (h *Handler) apiCreateComment(w http.ResponseWriter, r *http.Request, postID int) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	var input apiCommentInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	comment := &models.Comment{
+		PostID:   postID,
+		ParentID: input.ParentID,
+		Author:   user.Username,
+		Text:     input.Text,
+	}
+
+	if err := h.services.CreateComment(comment); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, comment)
+}
+
+// apiUpdateComment changes the text of a comment of the signed-in user.
+func (h *Handler) apiUpdateComment(w http.ResponseWriter, r *http.Request, commentID int) {
+	var input apiCommentInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	comment, err := h.services.UpdateComment(user, commentID, input.Text)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, comment)
+}
+
+// apiDeleteComment deletes a comment of the signed-in user.
+func (h *Handler) apiDeleteComment(w http.ResponseWriter, r *http.Request, commentID int) {
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if _, err := h.services.Comment.DeleteComment(user, commentID); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiCommentRevisions lists the earlier texts of a comment for moderators.
+func (h *Handler) apiCommentRevisions(w http.ResponseWriter, r *http.Request, commentID int) {
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	revisions, err := h.services.GetCommentRevisions(user, commentID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+	if revisions == nil {
+		revisions = []models.CommentRevision{}
+	}
+
+	h.writeJSON(w, http.StatusOK, apiRevisionList{Revisions: revisions})
+}
+
+// apiReactToComment toggles a like or dislike of the signed-in user and returns the updated comment.
+func (h *Handler) apiReactToComment(w http.ResponseWriter, r *http.Request, commentID int, reaction string) {
+	if _, ok := h.apiVisibleComment(w, commentID); !ok {
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	var err error
+	if reaction == "like" {
+		err = h.services.LikeComment(commentID, user.Username)
+	} else {
+		err = h.services.DislikeComment(commentID, user.Username)
+	}
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.apiGetComment(w, commentID)
+}
@@ -0,0 +1,404 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// apiPostInput is the request body for creating or updating a post.
+// Updates only change the title and content, and the tags when they are given.
+type apiPostInput struct {
+	Title      string   `json:"title"`
+	About      string   `json:"about"`
+	Content    string   `json:"content"`
+	Categories []string `json:"categories"`
+	Tags       []string `json:"tags"`
+}
+
+// apiCommentList is the response body of comment listings.
+type apiCommentList struct {
+	Comments []*models.Comment `json:"comments"`
+}
+
+// apiPostHistory is the response body of the revisions of a post.
+type apiPostHistory struct {
+	Revisions []models.PostRevision `json:"revisions"`
+	From      int                   `json:"from"`
+	To        int                   `json:"to"`
+	Title     []models.DiffLine     `json:"title"`
+	Content   []models.DiffLine     `json:"content"`
+}
+
+// apiCategoryList is the response body of the category listing.
+type apiCategoryList struct {
+	Categories []models.Category `json:"categories"`
+}
+
+// apiPosts serves /api/v1/posts.
+func (h *Handler) apiPosts(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.apiGetPosts(w, r)
+	case http.MethodPost:
+		h.apiAuthenticate(service.ScopePost, h.apiCreatePost)(w, r)
+	default:
+		h.apiMethodNotAllowed(w)
+	}
+}
+
+// apiPost serves /api/v1/posts/{id} and its sub-resources.
+func (h *Handler) apiPost(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, "/api/v1/posts/")
+	if len(segments) == 0 || len(segments) > 3 || len(segments) == 3 && segments[1] != "reactions" {
+		h.apiNotFound(w)
+		return
+	}
+
+	postID, err := strconv.Atoi(segments[0])
+	if err != nil {
+		h.apiNotFound(w)
+		return
+	}
+
+	if len(segments) == 1 {
+		switch r.Method {
+		case http.MethodGet:
+			h.apiGetPost(w, postID)
+		case http.MethodPut:
+			h.apiAuthenticate(service.ScopePost, func(w http.ResponseWriter, r *http.Request) {
+				h.apiUpdatePost(w, r, postID)
+			})(w, r)
+		case http.MethodDelete:
+			h.apiAuthenticate(service.ScopePost, func(w http.ResponseWriter, r *http.Request) {
+				h.apiDeletePost(w, r, postID)
+			})(w, r)
+		default:
+			h.apiMethodNotAllowed(w)
+		}
+		return
+	}
+
+	switch segments[1] {
+	case "comments":
+		switch r.Method {
+		case http.MethodGet:
+			h.apiGetComments(w, postID)
+		case http.MethodPost:
+			h.apiAuthenticate(service.ScopeComment, func(w http.ResponseWriter, r *http.Request) {
+				h.apiCreateComment(w, r, postID)
+			})(w, r)
+		default:
+			h.apiMethodNotAllowed(w)
+		}
+	case "revisions":
+		if r.Method != http.MethodGet {
+			h.apiMethodNotAllowed(w)
+			return
+		}
+		h.apiPostRevisions(w, r, postID)
+	case "like", "dislike":
+		if r.Method != http.MethodPost {
+			h.apiMethodNotAllowed(w)
+			return
+		}
+		reaction := segments[1]
+		h.apiAuthenticate(service.ScopePost, func(w http.ResponseWriter, r *http.Request) {
+			h.apiReactToPost(w, r, postID, reaction)
+		})(w, r)
+	case "reactions":
+		if len(segments) == 3 {
+			if r.Method != http.MethodGet {
+				h.apiMethodNotAllowed(w)
+				return
+			}
+			h.apiGetPostReactionUsers(w, r, postID, segments[2])
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			h.apiGetPostReactions(w, h.apiViewer(r), postID)
+		case http.MethodPost:
+			h.apiAuthenticate(service.ScopePost, func(w http.ResponseWriter, r *http.Request) {
+				h.apiTogglePostReaction(w, r, postID)
+			})(w, r)
+		default:
+			h.apiMethodNotAllowed(w)
+		}
+	default:
+		h.apiNotFound(w)
+	}
+}
+
+// apiPageRequest reads the sort, cursor and limit query parameters of a post listing.
+// It answers 400 and returns false when the limit is not a positive number.
+func (h *Handler) apiPageRequest(w http.ResponseWriter, r *http.Request) (models.PageRequest, bool) {
+	query := r.URL.Query()
+	request := models.PageRequest{
+		Sort:   models.PostSort(query.Get("sort")),
+		Cursor: query.Get("cursor"),
+	}
+	if limit := query.Get("limit"); limit != "" {
+		var err error
+		if request.Limit, err = strconv.Atoi(limit); err != nil || request.Limit < 1 {
+			h.apiErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return models.PageRequest{}, false
+		}
+	}
+	return request, true
+}
+
+// apiGetPosts lists a page of posts in the order picked by ?sort=, optionally narrowed down by the filter
+// that readPostFilter reads. Keeping to the posts that the user wrote or liked needs a signed in user.
+// ?limit= sets the page size and ?cursor= takes the next cursor of the previous page.
+func (h *Handler) apiGetPosts(w http.ResponseWriter, r *http.Request) {
+	if readPostFilter(r).Personal() {
+		h.apiAuthenticate(service.ScopeRead, h.apiFilterPosts)(w, r)
+		return
+	}
+	h.apiFilterPosts(w, r)
+}
+
+// apiFilterPosts lists a page of the posts matching the filter of the request, for the user it was authenticated as, if any.
+func (h *Handler) apiFilterPosts(w http.ResponseWriter, r *http.Request) {
+	request, ok := h.apiPageRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user, _ := r.Context().Value(ctxKeyUser).(models.User)
+
+	filter, _, err := h.postFilter(readPostFilter(r), user)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	page, err := h.services.PostItem.FilterPosts(filter, request)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	if page.Posts == nil {
+		page.Posts = []models.Post{}
+	}
+
+	h.writeJSON(w, http.StatusOK, page)
+}
+
+// apiVisiblePost returns a post that is not hidden, or writes the not found error and reports false.
+func (h *Handler) apiVisiblePost(w http.ResponseWriter, postID int) (models.Post, bool) {
+	post, err := h.services.PostItem.GetPostByID(postID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return models.Post{}, false
+	}
+
+	if post.Hidden {
+		h.apiServiceError(w, service.ErrPostNotFound)
+		return models.Post{}, false
+	}
+
+	return post, true
+}
+
+// apiGetPost returns a single post.
+func (h *Handler) apiGetPost(w http.ResponseWriter, postID int) {
+	post, ok := h.apiVisiblePost(w, postID)
+	if !ok {
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, post)
+}
+
+// apiPostRevisions lists the versions of a post with the line diff between the two picked by ?from= and ?to=.
+func (h *Handler) apiPostRevisions(w http.ResponseWriter, r *http.Request, postID int) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	revisions, err := h.services.GetPostHistory(postID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	from, to, ok := revisionPair(revisions, r.URL.Query())
+	if !ok {
+		h.apiErrorResponse(w, http.StatusNotFound, "revision not found")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, apiPostHistory{
+		Revisions: revisions,
+		From:      from.Version,
+		To:        to.Version,
+		Title:     service.DiffLines(from.Title, to.Title),
+		Content:   service.DiffLines(from.Content, to.Content),
+	})
+}
+
+// apiCreatePost creates a post on behalf of the signed-in user.
+func (h *Handler) apiCreatePost(w http.ResponseWriter, r *http.Request) {
+	var input apiPostInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	post := &models.Post{
+		UserID:   user.ID,
+		Title:    input.Title,
+		About:    input.About,
+		Content:  input.Content,
+		Category: input.Categories,
+		Tags:     input.Tags,
+	}
+
+	if err := h.services.PostItem.CreatePost(post, nil); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, post)
+}
+
+// apiUpdatePost changes the title and content of a post the signed-in user may edit, and its tags when the body has them.
+func (h *Handler) apiUpdatePost(w http.ResponseWriter, r *http.Request, postID int) {
+	var input apiPostInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.PostItem.UpdatePost(user, postID, input.Title, input.Content, input.Tags); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	// The edited post is returned even when it is hidden, whoever may edit it may read it, like the edit form shows it.
+	post, err := h.services.PostItem.GetPostByID(postID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, post)
+}
+
+// apiDeletePost deletes a post owned by the signed-in user.
+func (h *Handler) apiDeletePost(w http.ResponseWriter, r *http.Request, postID int) {
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.DeletePost(user, postID); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// apiReactToPost toggles a like or dislike of the signed-in user and returns the updated post.
+func (h *Handler) apiReactToPost(w http.ResponseWriter, r *http.Request, postID int, reaction string) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	var err error
+	if reaction == "like" {
+		err = h.services.LikePost(user.Username, postID)
+	} else {
+		err = h.services.DisLikePost(user.Username, postID)
+	}
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.apiGetPost(w, postID)
+}
+
+// apiCategories lists the categories that posts can be filed under.
+func (h *Handler) apiCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	categories, err := h.services.GetCategories()
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+	if categories == nil {
+		categories = []models.Category{}
+	}
+
+	h.writeJSON(w, http.StatusOK, apiCategoryList{Categories: categories})
+}
+
+// apiCategory serves /api/v1/categories/{slug}/subscription, where PUT subscribes the user to the category
+// and DELETE unsubscribes them.
+func (h *Handler) apiCategory(w http.ResponseWriter, r *http.Request) {
+	segments := pathSegments(r.URL.Path, "/api/v1/categories/")
+	if len(segments) != 2 || segments[1] != "subscription" {
+		h.apiNotFound(w)
+		return
+	}
+	if r.Method != http.MethodPut && r.Method != http.MethodDelete {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	h.apiAuthenticate(service.ScopeAccount, func(w http.ResponseWriter, r *http.Request) {
+		category, err := h.services.GetCategoryBySlug(segments[0])
+		if err != nil {
+			h.apiServiceError(w, err)
+			return
+		}
+
+		user := r.Context().Value(ctxKeyUser).(models.User)
+		if _, err = h.services.SubscribeCategory(user, category.ID, r.Method == http.MethodPut); err != nil {
+			h.apiServiceError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})(w, r)
+}
+
+// apiFeed returns a page of the newest posts in the categories that the user subscribed to.
+func (h *Handler) apiFeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	request, ok := h.apiPageRequest(w, r)
+	if !ok {
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	page, err := h.services.PostItem.GetSubscribedPosts(user.ID, request)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+	if page.Posts == nil {
+		page.Posts = []models.Post{}
+	}
+
+	h.writeJSON(w, http.StatusOK, page)
+}
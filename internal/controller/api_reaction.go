@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+)
+
+// apiReactionInput is the request body that toggles a reaction.
+type apiReactionInput struct {
+	Emoji string `json:"emoji"`
+}
+
+// apiReactionList is the response body of the reactions to a post or a comment.
+type apiReactionList struct {
+	Reactions []models.Reaction `json:"reactions"`
+}
+
+// apiViewer returns the name of the user making a request that does not require signing in, or "" without one.
+func (h *Handler) apiViewer(r *http.Request) string {
+	user, _, err := h.requestUser(r)
+	if err != nil {
+		return ""
+	}
+	return user.Username
+}
+
+// apiGetPostReactions lists the reactions to a post with the first users who gave each.
+func (h *Handler) apiGetPostReactions(w http.ResponseWriter, viewer string, postID int) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	reactions, err := h.services.GetPostReactions(viewer, postID)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, apiReactionList{Reactions: reactions})
+}
+
+// apiTogglePostReaction toggles the reaction given in the body on a post and returns the reactions to the post.
+func (h *Handler) apiTogglePostReaction(w http.ResponseWriter, r *http.Request, postID int) {
+	var input apiReactionInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.ReactToPost(user.Username, postID, input.Emoji); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.apiGetPostReactions(w, user.Username, postID)
+}
+
+// apiGetCommentReactions lists the reactions to a comment with the first users who gave each.
+func (h *Handler) apiGetCommentReactions(w http.ResponseWriter, viewer string, commentID int) {
+	comment, ok := h.apiVisibleComment(w, commentID)
+	if !ok {
+		return
+	}
+
+	if err := h.services.SetCommentReactions(viewer, []*models.Comment{&comment}); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, apiReactionList{Reactions: comment.Reactions})
+}
+
+// apiToggleCommentReaction toggles the reaction given in the body on a comment and returns the reactions to the comment.
+func (h *Handler) apiToggleCommentReaction(w http.ResponseWriter, r *http.Request, commentID int) {
+	var input apiReactionInput
+	if err := decodeJSON(w, r, &input); err != nil {
+		h.apiErrorResponse(w, http.StatusBadRequest, "malformed request body")
+		return
+	}
+
+	if _, ok := h.apiVisibleComment(w, commentID); !ok {
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.ReactToComment(user.Username, commentID, input.Emoji); err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.apiGetCommentReactions(w, user.Username, commentID)
+}
+
+// apiGetPostReactionUsers lists a page of the users who gave a post the reaction named by the last path segment,
+// an emoji or like or dislike.
+func (h *Handler) apiGetPostReactionUsers(w http.ResponseWriter, r *http.Request, postID int, reaction string) {
+	if _, ok := h.apiVisiblePost(w, postID); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	users, err := h.services.GetPostReactionUsers(postID, reactionParam(reaction), page)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, users)
+}
+
+// apiGetCommentReactionUsers lists a page of the users who gave a comment the reaction named by the last path
+// segment, an emoji or like or dislike.
+func (h *Handler) apiGetCommentReactionUsers(w http.ResponseWriter, r *http.Request, commentID int, reaction string) {
+	if _, ok := h.apiVisibleComment(w, commentID); !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	users, err := h.services.GetCommentReactionUsers(commentID, reactionParam(reaction), page)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, users)
+}
@@ -0,0 +1,100 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"forum/internal/service.go"
+)
+
+const (
+	// maxPostBody bounds the body of a new post, its attachments plus room for the fields of the form.
+	maxPostBody = service.MaxPostAttachments*service.MaxAttachmentSize + 1<<20
+	// maxUploadMemory is how much of a multipart form is kept in memory, the rest goes to temporary files.
+	maxUploadMemory = 8 << 20
+)
+
+// readUploads parses a form, which may be multipart, and reads the files sent in field.
+// Files are read up to one byte more than service.MaxAttachmentSize, which is enough for the service to refuse them.
+func readUploads(w http.ResponseWriter, r *http.Request, field string) ([]models.Upload, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPostBody)
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+	if r.MultipartForm == nil {
+		return nil, nil
+	}
+
+	var uploads []models.Upload
+	for _, header := range r.MultipartForm.File[field] {
+		// Browsers send an empty part when no file was picked.
+		if header.Filename == "" && header.Size == 0 {
+			continue
+		}
+		file, err := header.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, service.MaxAttachmentSize+1))
+		file.Close()
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, models.Upload{Name: header.Filename, Data: data})
+	}
+	return uploads, nil
+}
+
+// attachment serves a file attached to a post at /attachments/{id}, and the thumbnail of an image at
+// /attachments/{id}/thumbnail. Images are shown in the browser, other files are downloaded.
+func (h *Handler) attachment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	idText, variant, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/attachments/"), "/")
+	id, err := strconv.Atoi(idText)
+	if err != nil || (variant != "" && variant != "thumbnail") {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+	thumbnail := variant == "thumbnail"
+
+	user := h.services.Authorization.GetSessionTokenFromRequest(r)
+
+	attachment, err := h.services.GetAttachment(user, id)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+	file, err := h.services.OpenAttachment(attachment, thumbnail)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+	defer file.Close()
+
+	contentType := attachment.ContentType
+	if thumbnail {
+		contentType = mime.TypeByExtension(path.Ext(attachment.ThumbnailKey))
+	}
+	disposition := "inline"
+	if !attachment.IsImage() {
+		disposition = "attachment"
+	}
+
+	// The type was sniffed on upload, browsers must not guess another one nor run anything the file holds.
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; sandbox")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", attachment.CreatedAt.Time, file)
+}
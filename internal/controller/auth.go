@@ -56,6 +56,13 @@ func (h *Handler) signUp(w http.ResponseWriter, r *http.Request) {
 				})
 				return
 			}
+			if errors.Is(err, service.ErrUsernameConfusable) {
+				w.WriteHeader(http.StatusBadRequest)
+				tmpl.Execute(w, RegisterError{
+					ErrorMessage: "The username looks too much like the name of another user",
+				})
+				return
+			}
 			h.errorPage(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -97,7 +104,7 @@ func (h *Handler) signIn(w http.ResponseWriter, r *http.Request) {
 		email := r.FormValue("form-email")
 		password := r.FormValue("form-password")
 
-		token, expiresAt, err := h.services.Authorization.GenerateSessionToken(email, password)
+		token, expiresAt, err := h.services.Authorization.GenerateSessionToken(email, password, r.UserAgent(), clientIP(r))
 		if err != nil {
 			w.WriteHeader(http.StatusBadRequest)
 			tmpl.Execute(w, LoginError{
@@ -141,3 +148,27 @@ func (h *Handler) LogOut(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+// logOutEverywhere ends every session of the current user, including this one.
+// It only answers POST, so that a link or an image on another page cannot sign the user out.
+func (h *Handler) logOutEverywhere(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err := h.services.DeleteUserSessions(user.ID); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    "sessionID",
+		Value:   "",
+		Expires: time.Now(),
+	})
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
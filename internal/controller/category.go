@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// categoriesPage represents the data needed to render the category administration page.
+type categoriesPage struct {
+	User       models.User
+	Categories []models.Category
+}
+
+// adminCategories lists every category, the archived ones included, with forms to change them.
+func (h *Handler) adminCategories(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/admin-categories.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	categories, err := h.services.GetAllCategories()
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := &categoriesPage{
+		User:       r.Context().Value(ctxKeyUser).(models.User),
+		Categories: categories,
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// createCategory adds a category with the posted name, slug and description.
+func (h *Handler) createCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	actor := r.Context().Value(ctxKeyUser).(models.User)
+	category := models.Category{
+		Name:        r.FormValue("name"),
+		Slug:        r.FormValue("slug"),
+		Description: r.FormValue("description"),
+	}
+
+	if _, err := h.services.CreateCategory(actor, category); err != nil {
+		h.categoryError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// updateCategory renames a category and changes its description and position.
+func (h *Handler) updateCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+	position, err := strconv.Atoi(r.FormValue("position"))
+	if err != nil {
+		h.errorPage(w, http.StatusBadRequest, "invalid position")
+		return
+	}
+
+	actor := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.UpdateCategory(actor, id, r.FormValue("name"), r.FormValue("description"), position); err != nil {
+		h.categoryError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// archiveCategory archives a category, or brings it back when archived is "false".
+func (h *Handler) archiveCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	actor := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.SetCategoryArchived(actor, id, r.FormValue("archived") != "false"); err != nil {
+		h.categoryError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/categories", http.StatusSeeOther)
+}
+
+// categoryError renders the error page matching an error returned by the category service.
+func (h *Handler) categoryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCategory):
+		h.errorPage(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrCategoryExists):
+		h.errorPage(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrCategoryNotFound):
+		h.errorPage(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		h.errorPage(w, http.StatusForbidden, err.Error())
+	default:
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -11,6 +11,13 @@ import (
 	"forum/internal/service.go"
 )
 
+// commentHistoryPage represents the data needed to render the revisions of a comment.
+type commentHistoryPage struct {
+	User      models.User
+	Comment   models.Comment
+	Revisions []models.CommentRevision
+}
+
 func (h *Handler) createComment(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
@@ -23,13 +30,32 @@ func (h *Handler) createComment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	author := r.FormValue("author")
-	input := r.FormValue("input")
+	parentID := 0
+	if value := r.FormValue("parentid"); value != "" {
+		if parentID, err = strconv.Atoi(value); err != nil {
+			h.errorPage(w, http.StatusBadRequest, service.ErrInvalidComment.Error())
+			return
+		}
+	}
+
+	post, err := h.services.PostItem.GetPostByID(postID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	if post.Hidden {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
 
 	comment := &models.Comment{
-		Author: author,
-		Text:   input,
-		PostID: postID,
+		Author:   user.Username,
+		Text:     r.FormValue("input"),
+		PostID:   postID,
+		ParentID: parentID,
 	}
 
 	if err := h.services.CreateComment(comment); err != nil {
@@ -61,13 +87,13 @@ func (h *Handler) likeComment(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.services.GetCommentByID(commentID)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.commentError(w, err)
 		return
 	}
 
-	err = h.services.Comment.LikeComment(commentID, username.Username)
+	err = h.services.LikeComment(commentID, username.Username)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.commentError(w, err)
 		return
 	}
 
@@ -91,15 +117,123 @@ func (h *Handler) disLikeComment(w http.ResponseWriter, r *http.Request) {
 
 	comment, err := h.services.GetCommentByID(commentID)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.commentError(w, err)
 		return
 	}
 
-	err = h.services.Comment.DislikeComment(commentID, username.Username)
+	err = h.services.DislikeComment(commentID, username.Username)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.commentError(w, err)
 		return
 	}
 
 	http.Redirect(w, r, fmt.Sprintf("/get-post/%v", comment.PostID), 302)
-}
\ No newline at end of file
+}
+
+// updateComment changes the text of a comment.
+func (h *Handler) updateComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	comment, err := h.services.UpdateComment(user, commentID, r.FormValue("input"))
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/get-post/%d", comment.PostID), http.StatusFound)
+}
+
+// deleteComment handles the deletion of a comment.
+func (h *Handler) deleteComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	comment, err := h.services.Comment.DeleteComment(user, commentID)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/get-post/%d", comment.PostID), http.StatusFound)
+}
+
+// commentHistory lists the earlier texts of a comment for moderators.
+func (h *Handler) commentHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/comment-history.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.URL.Query().Get("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	revisions, err := h.services.GetCommentRevisions(user, commentID)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	comment, err := h.services.GetCommentByID(commentID)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	page := &commentHistoryPage{
+		User:      user,
+		Comment:   comment,
+		Revisions: revisions,
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// commentError renders the error page matching an error returned by the comment service.
+func (h *Handler) commentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		h.errorPage(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		h.errorPage(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidComment),
+		errors.Is(err, service.ErrInvalidReaction),
+		errors.Is(err, service.ErrInvalidPage):
+		h.errorPage(w, http.StatusBadRequest, err.Error())
+	default:
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"forum/internal/service.go"
+)
+
+// postFilterRequest is a post filter as read from the query parameters of a listing.
+type postFilterRequest struct {
+	// Slugs are the categories picked, posts must be filed under any of them or, when MatchAll is set, all of them.
+	Slugs    []string
+	MatchAll bool
+	// Created and Liked keep to the posts that the user wrote or liked.
+	Created bool
+	Liked   bool
+	// Tags keeps to the posts that carry all of them.
+	Tags []string
+}
+
+// readPostFilter reads a post filter from the query parameters: category, repeated or separated by commas,
+// match set to "all" to want every category picked rather than any, created and liked set to true,
+// and tag, repeated or separated by commas like category.
+func readPostFilter(r *http.Request) postFilterRequest {
+	query := r.URL.Query()
+
+	var filter postFilterRequest
+	for _, value := range query["category"] {
+		for _, slug := range strings.Split(value, ",") {
+			if slug = strings.ToLower(strings.TrimSpace(slug)); slug != "" && !filter.Has(slug) {
+				filter.Slugs = append(filter.Slugs, slug)
+			}
+		}
+	}
+	filter.MatchAll = query.Get("match") == "all"
+	filter.Created, _ = strconv.ParseBool(query.Get("created"))
+	filter.Liked, _ = strconv.ParseBool(query.Get("liked"))
+	for _, value := range query["tag"] {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				filter.Tags = append(filter.Tags, tag)
+			}
+		}
+	}
+	return filter
+}
+
+// Personal tells whether the filter keeps to the posts of the user, which needs a signed in user.
+func (f postFilterRequest) Personal() bool {
+	return f.Created || f.Liked
+}
+
+// Has tells whether a category is picked.
+func (f postFilterRequest) Has(slug string) bool {
+	for _, picked := range f.Slugs {
+		if picked == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// Query encodes the filter back into query parameters, followed by & when it is not empty,
+// for the links that page through or reorder the filtered listing.
+func (f postFilterRequest) Query() template.URL {
+	values := url.Values{}
+	for _, slug := range f.Slugs {
+		values.Add("category", slug)
+	}
+	if f.MatchAll {
+		values.Set("match", "all")
+	}
+	if f.Created {
+		values.Set("created", "true")
+	}
+	if f.Liked {
+		values.Set("liked", "true")
+	}
+	for _, tag := range f.Tags {
+		values.Add("tag", tag)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return template.URL(values.Encode() + "&")
+}
+
+// postFilter turns a filter request of user into a models.PostFilter, together with the categories it picks.
+// Archived categories can be filtered by as well.
+func (h *Handler) postFilter(request postFilterRequest, user models.User) (models.PostFilter, []models.Category, error) {
+	filter := models.PostFilter{MatchAll: request.MatchAll, Tags: request.Tags}
+	if request.Created {
+		filter.CreatedBy = user.ID
+	}
+	if request.Liked {
+		filter.LikedBy = user.Username
+	}
+	if len(request.Slugs) == 0 {
+		return filter, nil, nil
+	}
+
+	all, err := h.services.GetAllCategories()
+	if err != nil {
+		return models.PostFilter{}, nil, err
+	}
+
+	var categories []models.Category
+	for _, slug := range request.Slugs {
+		found := false
+		for _, category := range all {
+			if category.Slug == slug {
+				filter.CategoryIDs = append(filter.CategoryIDs, category.ID)
+				categories = append(categories, category)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return models.PostFilter{}, nil, service.ErrCategoryNotFound
+		}
+	}
+	return filter, categories, nil
+}
@@ -1,17 +1,38 @@
 package controller
 
 import (
+	"forum/internal/markdown"
+	"forum/internal/models"
+	"html/template"
 	"net/http"
+	"path/filepath"
 
 	"forum/internal/service.go"
 )
 
+// Config holds the settings of the handlers.
+type Config struct {
+	// MaxCommentDepth is how many levels of replies get indented, deeper replies line up with the last level.
+	MaxCommentDepth int
+}
+
 type Handler struct {
 	services *service.Service
+	config   Config
 }
 
-func NewHandler(services *service.Service) *Handler {
-	return &Handler{services: services}
+func NewHandler(services *service.Service, config Config) *Handler {
+	return &Handler{services: services, config: config}
+}
+
+// layoutTemplate holds the head and the sidebar that page templates are rendered in.
+const layoutTemplate = "web/template/layout.html"
+
+// parseTemplate parses a page template together with the layout, whose sidebar lists the categories returned
+// by its categories function. Its markdown function renders the content of posts and comments.
+func (h *Handler) parseTemplate(file string) (*template.Template, error) {
+	funcs := template.FuncMap{"categories": h.services.GetCategories, "markdown": markdown.Render}
+	return template.New(filepath.Base(file)).Funcs(funcs).ParseFiles(layoutTemplate, file)
 }
 
 func (h *Handler) InitRoutes() *http.ServeMux {
@@ -23,23 +44,67 @@ func (h *Handler) InitRoutes() *http.ServeMux {
 
 	router.HandleFunc("/sign-up", h.signUp)
 	router.HandleFunc("/sign-in", h.signIn)
-	router.HandleFunc("/logout", h.authenticateUser(h.LogOut))
+	router.HandleFunc("/logout", h.authenticateUser(h.requireScope(service.ScopeAccount, h.LogOut)))
+	router.HandleFunc("/logout-all", h.authenticateUser(h.requireScope(service.ScopeAccount, h.logOutEverywhere)))
+	router.HandleFunc("/sessions", h.authenticateUser(h.requireScope(service.ScopeAccount, h.getSessions)))
+	router.HandleFunc("/sessions/revoke", h.authenticateUser(h.requireScope(service.ScopeAccount, h.revokeSession)))
+	router.HandleFunc("/tokens", h.authenticateUser(h.requireScope(service.ScopeAccount, h.accessTokens)))
+	router.HandleFunc("/tokens/revoke", h.authenticateUser(h.requireScope(service.ScopeAccount, h.revokeAccessToken)))
 
-	router.HandleFunc("/create-post", h.authenticateUser(h.createPost))
+	router.HandleFunc("/admin/users", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.adminUsers))))
+	router.HandleFunc("/admin/users/role", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.setUserRole))))
+	router.HandleFunc("/admin/categories", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.adminCategories))))
+	router.HandleFunc("/admin/categories/create", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.createCategory))))
+	router.HandleFunc("/admin/categories/update", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.updateCategory))))
+	router.HandleFunc("/admin/categories/archive", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleAdministrator, h.archiveCategory))))
+
+	router.HandleFunc("/create-post", h.authenticateUser(h.requireScope(service.ScopePost, h.createPost)))
 	router.HandleFunc("/get-post/", h.getPost)
+	router.HandleFunc("/attachments/", h.attachment)
 	router.HandleFunc("/get-posts-by-category/", h.getPostsByCategory)
-	router.HandleFunc("/get-created-posts/", h.authenticateUser(h.getCreatedPost))
-	router.HandleFunc("/get-liked-posts/", h.authenticateUser(h.getLikedPost))
+	router.HandleFunc("/get-created-posts/", h.authenticateUser(h.requireScope(service.ScopeRead, h.getCreatedPost)))
+	router.HandleFunc("/get-liked-posts/", h.authenticateUser(h.requireScope(service.ScopeRead, h.getLikedPost)))
+	router.HandleFunc("/my-feed", h.authenticateUser(h.requireScope(service.ScopeRead, h.getSubscribedPosts)))
+	router.HandleFunc("/subscribe-category", h.authenticateUser(h.requireScope(service.ScopeAccount, h.subscribeCategory)))
+
+	router.HandleFunc("/like/", h.authenticateUser(h.requireScope(service.ScopePost, h.likePost)))
+	router.HandleFunc("/dislike/", h.authenticateUser(h.requireScope(service.ScopePost, h.disLikePost)))
+	router.HandleFunc("/react/", h.authenticateUser(h.requireScope(service.ScopePost, h.reactToPost)))
+	router.HandleFunc("/post-reactions/", h.getPostReactionUsers)
+
+	router.HandleFunc("/create-comment", h.authenticateUser(h.requireScope(service.ScopeComment, h.createComment)))
+	router.HandleFunc("/comment-like/", h.authenticateUser(h.requireScope(service.ScopeComment, h.likeComment)))
+	router.HandleFunc("/comment-dislike/", h.authenticateUser(h.requireScope(service.ScopeComment, h.disLikeComment)))
+	router.HandleFunc("/comment-react/", h.authenticateUser(h.requireScope(service.ScopeComment, h.reactToComment)))
+	router.HandleFunc("/comment-reactions/", h.getCommentReactionUsers)
+	router.HandleFunc("/update-comment", h.authenticateUser(h.requireScope(service.ScopeComment, h.updateComment)))
+	router.HandleFunc("/delete-comment", h.authenticateUser(h.requireScope(service.ScopeComment, h.deleteComment)))
+	router.HandleFunc("/comment-history", h.authenticateUser(h.requireScope(service.ScopeRead, h.requireRole(models.RoleModerator, h.commentHistory))))
+
+	router.HandleFunc("/report", h.authenticateUser(h.reportContent))
+	router.HandleFunc("/moderation", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleModerator, h.moderationQueue))))
+	router.HandleFunc("/moderation/resolve", h.authenticateUser(h.requireScope(service.ScopeAccount, h.requireRole(models.RoleModerator, h.resolveReport))))
 
-	router.HandleFunc("/like/", h.authenticateUser(h.likePost))
-	router.HandleFunc("/dislike/", h.authenticateUser(h.disLikePost))
+	router.HandleFunc("/update-post", h.authenticateUser(h.requireScope(service.ScopePost, h.updatePost)))
+	router.HandleFunc("/post-revisions/", h.getPostRevisions)
+	router.HandleFunc("/delete", h.authenticateUser(h.requireScope(service.ScopePost, h.deletePost)))
 
-	router.HandleFunc("/create-comment", h.authenticateUser(h.createComment))
-	router.HandleFunc("/comment-like/", h.authenticateUser(h.likeComment))
-	router.HandleFunc("/comment-dislike/", h.authenticateUser(h.disLikeComment))
+	router.HandleFunc("/search", h.search)
+	router.HandleFunc("/tags", h.tags)
 
-	router.HandleFunc("/update-post", h.authenticateUser(h.updatePost))
-	router.HandleFunc("/delete", h.authenticateUser(h.deletePost))
+	router.HandleFunc("/api/v1/", h.apiUnknown)
+	router.HandleFunc("/api/v1/auth/sign-up", h.apiSignUp)
+	router.HandleFunc("/api/v1/auth/sign-in", h.apiSignIn)
+	router.HandleFunc("/api/v1/auth/logout", h.apiAuthenticate(service.ScopeAccount, h.apiLogOut))
+	router.HandleFunc("/api/v1/me", h.apiAuthenticate(service.ScopeRead, h.apiMe))
+	router.HandleFunc("/api/v1/posts", h.apiPosts)
+	router.HandleFunc("/api/v1/posts/", h.apiPost)
+	router.HandleFunc("/api/v1/comments/", h.apiComment)
+	router.HandleFunc("/api/v1/categories", h.apiCategories)
+	router.HandleFunc("/api/v1/categories/", h.apiCategory)
+	router.HandleFunc("/api/v1/feed", h.apiAuthenticate(service.ScopeRead, h.apiFeed))
+	router.HandleFunc("/api/v1/search", h.apiSearch)
+	router.HandleFunc("/api/v1/tags", h.apiTags)
 
 	return router
 }
\ No newline at end of file
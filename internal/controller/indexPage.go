@@ -2,13 +2,29 @@ package controller
 
 import (
 	"forum/internal/models"
+	"html/template"
 	"net/http"
-	"text/template"
 )
 
 type Index struct {
-	User models.User
-	Post []models.Post
+	User     models.User
+	Post     []models.Post
+	Category models.Category
+	Sort     models.PostSort
+	Sorts    []models.PostSort
+	// Cursor is the cursor of the page shown, empty on the first page.
+	Cursor string
+	// Next is the cursor of the following page, empty on the last page.
+	Next string
+	// Filtered is set on the filtered listing, whose filter is Filter and whose picked categories are Categories.
+	Filtered   bool
+	Filter     postFilterRequest
+	Categories []models.Category
+	// Subscribed tells whether the user subscribed to Category.
+	Subscribed bool
+	// Feed is set on the feed of the subscribed categories, which lists them in Subscriptions.
+	Feed          bool
+	Subscriptions []models.Category
 }
 
 func (h *Handler) indexPage(w http.ResponseWriter, r *http.Request) {
@@ -21,22 +37,42 @@ func (h *Handler) indexPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := template.Must(template.ParseFiles("web/template/index.html"))
+	tmpl := template.Must(h.parseTemplate("web/template/index.html"))
 
 	user := h.services.Authorization.GetSessionTokenFromRequest(r)
 
-	posts, err := h.services.PostItem.GetAllPosts()
+	request := requestPage(r)
+
+	page, err := h.services.PostItem.GetAllPosts(request)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.postError(w, err)
 		return
 	}
 
 	index := &Index{
-		User: user,
-		Post: posts,
+		User:   user,
+		Post:   page.Posts,
+		Sort:   request.Sort,
+		Sorts:  models.PostSorts,
+		Cursor: request.Cursor,
+		Next:   page.Next,
 	}
 
 	if err = tmpl.Execute(w, index); err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 	}
-}
\ No newline at end of file
+}
+
+// requestPage returns the page of a post feed picked by the sort and cursor query parameters.
+// Without a sort order the newest posts come first.
+func requestPage(r *http.Request) models.PageRequest {
+	query := r.URL.Query()
+	page := models.PageRequest{
+		Sort:   models.PostSort(query.Get("sort")),
+		Cursor: query.Get("cursor"),
+	}
+	if page.Sort == "" {
+		page.Sort = models.SortNew
+	}
+	return page
+}
@@ -2,9 +2,13 @@ package controller
 
 import (
 	"context"
+	"errors"
 	"forum/internal/models"
 	"net/http"
+	"strings"
 	"time"
+
+	"forum/internal/service.go"
 )
 
 // ctxKey is a custom type used as a key for context values in middleware functions.
@@ -13,28 +17,17 @@ type ctxKey int
 const (
 	// ctxKeyUser is a context key for storing user information in middleware functions.
 	ctxKeyUser ctxKey = iota
+	// ctxKeyScopes is a context key for storing the scopes granted to the request.
+	ctxKeyScopes
 )
 
+// errSessionExpired is returned by requestUser when the session cookie belongs to an expired session.
+var errSessionExpired = errors.New("session expired")
+
 func (h *Handler) authenticateUser(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var (
-			user models.User
-			err  error
-		)
-
-		cookie, err := r.Cookie("sessionID")
-		if err != nil {
-			h.errorPage(w, http.StatusUnauthorized, err.Error())
-			return
-		}
-
-		user, err = h.services.GetSessionToken(cookie.Value)
-		if err != nil {
-			h.errorPage(w, http.StatusUnauthorized, err.Error())
-			return
-		}
-
-		if err != nil || user.ExpiresAt.Before(time.Now()) {
+		user, scopes, err := h.requestUser(r)
+		if errors.Is(err, errSessionExpired) {
 			// Clear the invalid or expired session cookie
 			http.SetCookie(w, &http.Cookie{
 				Name:    "sessionID",
@@ -46,7 +39,76 @@ func (h *Handler) authenticateUser(next http.HandlerFunc) http.HandlerFunc {
 			h.errorPage(w, http.StatusUnauthorized, "Session expired. Please log in again.")
 			return
 		}
+		if err != nil {
+			h.errorPage(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user, scopes)))
+	}
+}
+
+// requireScope lets the request through only if it was granted scope.
+// It must be wrapped by authenticateUser.
+func (h *Handler) requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !requestHasScope(r, scope) {
+			h.errorPage(w, http.StatusForbidden, "access token is missing the "+scope+" scope")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requireRole lets the request through only if the user holds at least the given role.
+// It must be wrapped by authenticateUser.
+func (h *Handler) requireRole(min models.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(ctxKeyUser).(models.User)
+		if !user.Role.AtLeast(min) {
+			h.errorPage(w, http.StatusForbidden, "this page requires the "+string(min)+" role")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// requestUser returns the user that sent the request and the scopes granted to it.
+// A personal access token in the Authorization header takes precedence over the session cookie.
+func (h *Handler) requestUser(r *http.Request) (models.User, []string, error) {
+	if header := r.Header.Get("Authorization"); header != "" {
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return models.User{}, nil, errors.New("unsupported authorization scheme")
+		}
+		return h.services.GetUserByAccessToken(strings.TrimSpace(token))
+	}
+
+	cookie, err := r.Cookie("sessionID")
+	if err != nil {
+		return models.User{}, nil, err
+	}
+
+	user, err := h.services.GetSessionToken(cookie.Value)
+	if err != nil {
+		return models.User{}, nil, err
+	}
 
-		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxKeyUser, user)))
+	if user.ExpiresAt.Before(time.Now()) {
+		return models.User{}, nil, errSessionExpired
 	}
+
+	return user, service.SessionScopes, nil
+}
+
+// withUser stores the authenticated user and its scopes in the context.
+func withUser(ctx context.Context, user models.User, scopes []string) context.Context {
+	ctx = context.WithValue(ctx, ctxKeyUser, user)
+	return context.WithValue(ctx, ctxKeyScopes, scopes)
+}
+
+// requestHasScope reports whether the authenticated request was granted scope.
+func requestHasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value(ctxKeyScopes).([]string)
+	return service.HasScope(scopes, scope)
 }
@@ -0,0 +1,138 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// moderationPage represents the data needed to render the moderation queue.
+type moderationPage struct {
+	User     models.User
+	Open     []models.Report
+	Resolved []models.Report
+}
+
+// reportContent flags a post or a comment for moderators.
+// It is routed without a scope and checks the scope of what is reported itself.
+func (h *Handler) reportContent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	// A report needs the scope that creating what it reports would need.
+	commentID, err := strconv.Atoi(r.FormValue("commentid"))
+	scope := service.ScopePost
+	if err == nil {
+		scope = service.ScopeComment
+	}
+	if !requestHasScope(r, scope) {
+		h.errorPage(w, http.StatusForbidden, "access token is missing the "+scope+" scope")
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+	reason := r.FormValue("reason")
+
+	var postID int
+	if scope == service.ScopeComment {
+		comment, err := h.services.ReportComment(user, commentID, reason)
+		if err != nil {
+			h.reportError(w, err)
+			return
+		}
+		postID = comment.PostID
+	} else {
+		postID, err = strconv.Atoi(r.FormValue("postid"))
+		if err != nil {
+			h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+			return
+		}
+		if err = h.services.ReportPost(user, postID, reason); err != nil {
+			h.reportError(w, err)
+			return
+		}
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/get-post/%d", postID), http.StatusFound)
+}
+
+// moderationQueue lists the open reports and the latest moderation actions.
+func (h *Handler) moderationQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/moderation.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	open, resolved, err := h.services.GetModerationQueue(user)
+	if err != nil {
+		h.reportError(w, err)
+		return
+	}
+
+	page := &moderationPage{
+		User:     user,
+		Open:     open,
+		Resolved: resolved,
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// resolveReport applies the action a moderator picked for a report.
+func (h *Handler) resolveReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	reportID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.ResolveReport(user, reportID, models.ReportStatus(r.FormValue("action"))); err != nil {
+		h.reportError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/moderation", http.StatusSeeOther)
+}
+
+// reportError renders the error page matching an error returned by the moderation service.
+func (h *Handler) reportError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound),
+		errors.Is(err, service.ErrCommentNotFound),
+		errors.Is(err, service.ErrReportNotFound):
+		h.errorPage(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrInvalidReport),
+		errors.Is(err, service.ErrInvalidAction):
+		h.errorPage(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrAlreadyReported),
+		errors.Is(err, service.ErrReportClosed):
+		h.errorPage(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		h.errorPage(w, http.StatusForbidden, err.Error())
+	default:
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
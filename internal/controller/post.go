@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"forum/internal/models"
 	"html/template"
-	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -15,14 +15,26 @@ import (
 
 // index represents the data needed to render the index page.
 type index struct {
-	User     models.User
-	Post     *models.Post
-	Comments []*models.Comment
+	User      models.User
+	Post      *models.Post
+	Comments  []*models.Comment
+	CanModify bool
+}
+
+// postRevisionsPage represents the data needed to render the revisions of a post.
+type postRevisionsPage struct {
+	User        models.User
+	Post        models.Post
+	Revisions   []models.PostRevision
+	From        models.PostRevision
+	To          models.PostRevision
+	TitleDiff   []models.DiffLine
+	ContentDiff []models.DiffLine
 }
 
 // createPost handles the creation of a new post.
 func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("web/template/create-post.html")
+	tmpl, err := h.parseTemplate("web/template/create-post.html")
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 		return
@@ -45,7 +57,16 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	case http.MethodPost:
-		r.ParseForm()
+		uploads, err := readUploads(w, r, "attachments")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				h.errorPage(w, http.StatusRequestEntityTooLarge, err.Error())
+				return
+			}
+			h.errorPage(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		title := r.FormValue("title")
 		content := r.FormValue("content")
 		about := r.FormValue("about")
@@ -57,10 +78,12 @@ func (h *Handler) createPost(w http.ResponseWriter, r *http.Request) {
 			Content:  content,
 			About:    about,
 			Category: categoryString,
+			Tags:     r.Form["tags"],
 		}
 
-		if err = h.services.PostItem.CreatePost(post); err != nil {
-			if errors.Is(err, service.ErrInvalidPost) {
+		if err = h.services.PostItem.CreatePost(post, uploads); err != nil {
+			if errors.Is(err, service.ErrInvalidPost) || errors.Is(err, service.ErrInvalidTag) ||
+				errors.Is(err, service.ErrInvalidAttachment) {
 				h.errorPage(w, http.StatusBadRequest, err.Error())
 				return
 			}
@@ -81,21 +104,56 @@ func (h *Handler) getPostsByCategory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := template.Must(template.ParseFiles("web/template/index.html"))
+	tmpl := template.Must(h.parseTemplate("web/template/index.html"))
 
 	user := h.services.Authorization.GetSessionTokenFromRequest(r)
 
-	category := r.URL.Query().Get("category")
+	request := readPostFilter(r)
+	if request.Personal() && user.ID == 0 {
+		h.errorPage(w, http.StatusUnauthorized, http.StatusText(http.StatusUnauthorized))
+		return
+	}
 
-	posts, err := h.services.PostItem.GetPostsByCategory(category)
+	filter, categories, err := h.postFilter(request, user)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.postError(w, err)
+		return
+	}
+	pageRequest := requestPage(r)
+
+	page, err := h.services.PostItem.FilterPosts(filter, pageRequest)
+	if err != nil {
+		h.postError(w, err)
 		return
 	}
 
 	index := &Index{
-		User: user,
-		Post: posts,
+		User:       user,
+		Post:       page.Posts,
+		Sort:       pageRequest.Sort,
+		Sorts:      models.PostSorts,
+		Cursor:     pageRequest.Cursor,
+		Next:       page.Next,
+		Filter:     request,
+		Filtered:   true,
+		Categories: categories,
+	}
+
+	// A single category gets its own heading with the subscription toggle.
+	if len(categories) == 1 {
+		index.Category = categories[0]
+		if user.ID != 0 {
+			subscriptions, err := h.services.GetSubscribedCategories(user.ID)
+			if err != nil {
+				h.postError(w, err)
+				return
+			}
+			for _, subscribed := range subscriptions {
+				if subscribed.ID == index.Category.ID {
+					index.Subscribed = true
+				}
+			}
+		}
 	}
 
 	if err = tmpl.Execute(w, index); err != nil {
@@ -111,7 +169,7 @@ func (h *Handler) getPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl, err := template.ParseFiles("web/template/get-post.html")
+	tmpl, err := h.parseTemplate("web/template/get-post.html")
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 		return
@@ -127,19 +185,44 @@ func (h *Handler) getPost(w http.ResponseWriter, r *http.Request) {
 
 	post, err := h.services.PostItem.GetPostByID(postID)
 	if err != nil {
+		if errors.Is(err, service.ErrPostNotFound) {
+			h.errorPage(w, http.StatusNotFound, err.Error())
+			return
+		}
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	if post.Hidden && !user.Role.AtLeast(models.RoleModerator) {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
 	comments, err := h.services.Comment.GetComments(postID)
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	for _, comment := range comments {
+		if comment.Depth > h.config.MaxCommentDepth {
+			comment.Depth = h.config.MaxCommentDepth
+		}
+	}
+
+	if post.Reactions, err = h.services.GetPostReactions(user.Username, postID); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if err = h.services.SetCommentReactions(user.Username, comments); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
 	index := &index{
-		User:     user,
-		Post:     &post,
-		Comments: comments,
+		User:      user,
+		Post:      &post,
+		Comments:  comments,
+		CanModify: h.services.AuthorizePostChange(user, post) == nil,
 	}
 
 	if err = tmpl.Execute(w, index); err != nil {
@@ -157,22 +240,93 @@ func (h *Handler) getCreatedPost(w http.ResponseWriter, r *http.Request) {
 	userRaw := r.Context().Value(ctxKeyUser)
 	user := userRaw.(models.User)
 
-	posts, err := h.services.PostItem.GetCreatedPosts(user.ID)
+	request := requestPage(r)
+
+	page, err := h.services.PostItem.GetCreatedPosts(user.ID, request)
 	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	index := &Index{
+		User:   user,
+		Post:   page.Posts,
+		Sort:   request.Sort,
+		Sorts:  models.PostSorts,
+		Cursor: request.Cursor,
+		Next:   page.Next,
+	}
+
+	tmpl := template.Must(h.parseTemplate("web/template/index.html"))
+	if err = tmpl.Execute(w, index); err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 	}
+}
+
+// getSubscribedPosts shows the feed of the newest posts in the categories that the user subscribed to.
+func (h *Handler) getSubscribedPosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	request := requestPage(r)
+
+	page, err := h.services.PostItem.GetSubscribedPosts(user.ID, request)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	subscriptions, err := h.services.GetSubscribedCategories(user.ID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
 
 	index := &Index{
-		User: user,
-		Post: posts,
+		User:          user,
+		Post:          page.Posts,
+		Sort:          models.SortNew,
+		Cursor:        request.Cursor,
+		Next:          page.Next,
+		Feed:          true,
+		Subscriptions: subscriptions,
 	}
 
-	tmpl := template.Must(template.ParseFiles("web/template/index.html"))
+	tmpl := template.Must(h.parseTemplate("web/template/index.html"))
 	if err = tmpl.Execute(w, index); err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 	}
 }
 
+// subscribeCategory subscribes the user to a category, or unsubscribes them when subscribed is "false",
+// and goes back to the category.
+func (h *Handler) subscribeCategory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	categoryID, err := strconv.Atoi(r.FormValue("categoryid"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	category, err := h.services.SubscribeCategory(user, categoryID, r.FormValue("subscribed") != "false")
+	if err != nil {
+		h.categoryError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/get-posts-by-category/?category="+url.QueryEscape(category.Slug), http.StatusSeeOther)
+}
+
 // getLikedPost handles the retrieval of posts liked by the user.
 func (h *Handler) getLikedPost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -183,18 +337,24 @@ func (h *Handler) getLikedPost(w http.ResponseWriter, r *http.Request) {
 	userRaw := r.Context().Value(ctxKeyUser)
 	user := userRaw.(models.User)
 
-	posts, err := h.services.PostItem.GetLikedPosts(user.Username)
+	request := requestPage(r)
+
+	page, err := h.services.PostItem.GetLikedPosts(user.Username, request)
 	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		h.postError(w, err)
 		return
 	}
 
 	index := &Index{
-		User: user,
-		Post: posts,
+		User:   user,
+		Post:   page.Posts,
+		Sort:   request.Sort,
+		Sorts:  models.PostSorts,
+		Cursor: request.Cursor,
+		Next:   page.Next,
 	}
 
-	tmpl := template.Must(template.ParseFiles("web/template/index.html"))
+	tmpl := template.Must(h.parseTemplate("web/template/index.html"))
 	err = tmpl.Execute(w, index)
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
@@ -215,11 +375,10 @@ func (h *Handler) likePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	username := r.FormValue("username")
+	user := r.Context().Value(ctxKeyUser).(models.User)
 
-	if err = h.services.LikePost(username, id); err != nil {
-		log.Println(err)
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	if err = h.services.LikePost(user.Username, id); err != nil {
+		h.postError(w, err)
 		return
 	}
 
@@ -228,16 +387,21 @@ func (h *Handler) likePost(w http.ResponseWriter, r *http.Request) {
 
 // disLikePost handles the disliking of a post.
 func (h *Handler) disLikePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
 	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/dislike/"))
 	if err != nil {
 		h.errorPage(w, http.StatusNotFound, err.Error())
 		return
 	}
 
-	username := r.FormValue("username")
+	user := r.Context().Value(ctxKeyUser).(models.User)
 
-	if err = h.services.DisLikePost(username, id); err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	if err = h.services.DisLikePost(user.Username, id); err != nil {
+		h.postError(w, err)
 		return
 	}
 
@@ -246,56 +410,187 @@ func (h *Handler) disLikePost(w http.ResponseWriter, r *http.Request) {
 
 // updatePost handles the updating of a post.
 func (h *Handler) updatePost(w http.ResponseWriter, r *http.Request) {
-	tmpl, err := template.ParseFiles("web/partials/layout.html", "web/template/editpost.html", "web/template/privat.navbar.html")
+	tmpl, err := h.parseTemplate("web/template/editpost.html")
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
 	id, err := strconv.Atoi(r.FormValue("id"))
 	if err != nil {
 		h.errorPage(w, http.StatusNotFound, err.Error())
 		return
 	}
 
-	post, err := h.services.PostItem.GetPostByID(id)
-	if err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	switch r.Method {
+	case http.MethodGet:
+		post, err := h.services.PostItem.GetPostByID(id)
+		if err != nil {
+			h.postError(w, err)
+			return
+		}
+
+		if err = h.services.AuthorizePostChange(user, post); err != nil {
+			h.postError(w, err)
+			return
+		}
+
+		index := &index{
+			User: user,
+			Post: &post,
+		}
+
+		if err = tmpl.Execute(w, index); err != nil {
+			h.errorPage(w, http.StatusInternalServerError, err.Error())
+		}
+	case http.MethodPost:
+		title := r.FormValue("title")
+		content := r.FormValue("content")
+		tags := r.PostForm["tags"]
+
+		if err = h.services.PostItem.UpdatePost(user, id, title, content, tags); err != nil {
+			h.postError(w, err)
+			return
+		}
+
+		http.Redirect(w, r, fmt.Sprintf("/get-post/%d", id), 302)
+	default:
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+	}
+}
+
+// deletePost handles the deletion of a post.
+func (h *Handler) deletePost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
 		return
 	}
 
-	index := &index{
-		Post: &post,
+	id, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, err.Error())
+		return
 	}
 
-	if r.Method == "GET" {
-		tmpl.Execute(w, index)
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.DeletePost(user, id); err != nil {
+		h.postError(w, err)
 		return
 	}
 
-	title := r.FormValue("title")
-	content := r.FormValue("content")
+	http.Redirect(w, r, "/", 302)
+}
+
+// getPostRevisions lists the versions of a post and shows the difference between two of them.
+func (h *Handler) getPostRevisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
 
-	err = h.services.PostItem.UpdatePost(id, post.Like, post.DisLike, title, content)
+	tmpl, err := h.parseTemplate("web/template/post-revisions.html")
 	if err != nil {
 		h.errorPage(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	http.Redirect(w, r, "/", 302)
-}
+	user := h.services.Authorization.GetSessionTokenFromRequest(r)
 
-// deletePost handles the deletion of a post.
-func (h *Handler) deletePost(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(r.FormValue("id"))
+	postID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/post-revisions/"))
 	if err != nil {
-		h.errorPage(w, http.StatusNotFound, err.Error())
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
 		return
 	}
 
-	if err = h.services.DeletePost(id); err != nil {
-		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	post, err := h.services.PostItem.GetPostByID(postID)
+	if err != nil {
+		h.postError(w, err)
 		return
 	}
 
-	http.Redirect(w, r, "/", 302)
+	if post.Hidden && !user.Role.AtLeast(models.RoleModerator) {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	revisions, err := h.services.GetPostHistory(postID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	from, to, ok := revisionPair(revisions, r.URL.Query())
+	if !ok {
+		h.errorPage(w, http.StatusNotFound, "revision not found")
+		return
+	}
+
+	page := &postRevisionsPage{
+		User:        user,
+		Post:        post,
+		Revisions:   revisions,
+		From:        from,
+		To:          to,
+		TitleDiff:   service.DiffLines(from.Title, to.Title),
+		ContentDiff: service.DiffLines(from.Content, to.Content),
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// revisionPair picks the versions named by the from and to query parameters.
+// Without them the current version is compared with the one before it.
+func revisionPair(revisions []models.PostRevision, query url.Values) (from, to models.PostRevision, ok bool) {
+	version := func(key string, fallback int) (models.PostRevision, bool) {
+		n := fallback
+		if value := query.Get(key); value != "" {
+			var err error
+			if n, err = strconv.Atoi(value); err != nil {
+				return models.PostRevision{}, false
+			}
+		}
+		if n < 1 || n > len(revisions) {
+			return models.PostRevision{}, false
+		}
+		return revisions[n-1], true
+	}
+
+	to, ok = version("to", len(revisions))
+	if !ok {
+		return from, to, false
+	}
+	previous := to.Version - 1
+	if previous < 1 {
+		previous = 1
+	}
+	from, ok = version("from", previous)
+	return from, to, ok
+}
+
+// postError renders the error page matching an error returned by the post service.
+func (h *Handler) postError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrPostNotFound),
+		errors.Is(err, service.ErrCategoryNotFound),
+		errors.Is(err, service.ErrAttachmentNotFound):
+		h.errorPage(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrForbidden):
+		h.errorPage(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrInvalidPost),
+		errors.Is(err, service.ErrInvalidSort),
+		errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidFilter),
+		errors.Is(err, service.ErrInvalidTag),
+		errors.Is(err, service.ErrInvalidAttachment),
+		errors.Is(err, service.ErrInvalidReaction),
+		errors.Is(err, service.ErrInvalidPage):
+		h.errorPage(w, http.StatusBadRequest, err.Error())
+	default:
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
 }
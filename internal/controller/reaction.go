@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"fmt"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// reactToPost toggles the reaction picked in the emoji field of the form on a post.
+func (h *Handler) reactToPost(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/react/"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.ReactToPost(user.Username, id, r.FormValue("emoji")); err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/get-post/%v", id), http.StatusFound)
+}
+
+// reactToComment toggles the reaction picked in the emoji field of the form on a comment.
+func (h *Handler) reactToComment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	commentID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/comment-react/"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	comment, err := h.services.GetCommentByID(commentID)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	if err = h.services.ReactToComment(user.Username, commentID, r.FormValue("emoji")); err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/get-post/%d#comment-%d", comment.PostID, commentID), http.StatusFound)
+}
+
+// reactionUsersPage represents the data needed to render the users who gave a reaction to a post or a comment.
+type reactionUsersPage struct {
+	User models.User
+	// Title names the post or the comment and Back links to it.
+	Title string
+	Back  string
+	// Path is the page itself, which the links to other reactions and pages add their query to.
+	Path      string
+	Reactions []models.Reaction
+	Users     models.ReactionUserPage
+	Previous  int
+	Next      int
+}
+
+// reactionParam reads the reaction to list users of from a query parameter or a path segment,
+// which is an emoji or the name like or dislike.
+func reactionParam(value string) string {
+	switch value {
+	case "", "like":
+		return models.ReactionLike
+	case "dislike":
+		return models.ReactionDislike
+	}
+	return value
+}
+
+// getPostReactionUsers lists the users who gave a post the reaction in the query parameter reaction, likes by
+// default, a page at a time.
+func (h *Handler) getPostReactionUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	user := h.services.Authorization.GetSessionTokenFromRequest(r)
+
+	postID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/post-reactions/"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	post, err := h.services.PostItem.GetPostByID(postID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	if post.Hidden && !user.Role.AtLeast(models.RoleModerator) {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	reactions, err := h.services.GetPostReactions(user.Username, postID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	users, err := h.services.GetPostReactionUsers(postID, reactionParam(r.URL.Query().Get("reaction")), page)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	h.renderReactionUsers(w, &reactionUsersPage{
+		User:      user,
+		Title:     post.Title,
+		Back:      fmt.Sprintf("/get-post/%d", postID),
+		Path:      r.URL.Path,
+		Reactions: reactions,
+		Users:     users,
+	})
+}
+
+// getCommentReactionUsers lists the users who gave a comment the reaction in the query parameter reaction, likes by
+// default, a page at a time.
+func (h *Handler) getCommentReactionUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	user := h.services.Authorization.GetSessionTokenFromRequest(r)
+
+	commentID, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/comment-reactions/"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	comment, err := h.services.GetCommentByID(commentID)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	post, err := h.services.PostItem.GetPostByID(comment.PostID)
+	if err != nil {
+		h.postError(w, err)
+		return
+	}
+
+	if comment.Deleted || (comment.Hidden || post.Hidden) && !user.Role.AtLeast(models.RoleModerator) {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	if err = h.services.SetCommentReactions(user.Username, []*models.Comment{&comment}); err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	users, err := h.services.GetCommentReactionUsers(commentID, reactionParam(r.URL.Query().Get("reaction")), page)
+	if err != nil {
+		h.commentError(w, err)
+		return
+	}
+
+	h.renderReactionUsers(w, &reactionUsersPage{
+		User:      user,
+		Title:     fmt.Sprintf("%s's comment on %s", comment.Author, post.Title),
+		Back:      fmt.Sprintf("/get-post/%d#comment-%d", comment.PostID, commentID),
+		Path:      r.URL.Path,
+		Reactions: comment.Reactions,
+		Users:     users,
+	})
+}
+
+func (h *Handler) renderReactionUsers(w http.ResponseWriter, page *reactionUsersPage) {
+	tmpl, err := h.parseTemplate("web/template/reaction-users.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page.Previous = page.Users.Page - 1
+	page.Next = page.Users.Page + 1
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
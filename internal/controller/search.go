@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// searchPage represents the data needed to render the search form and its results.
+type searchPage struct {
+	User     models.User
+	Query    string
+	Category string
+	Author   string
+	Results  models.SearchPage
+	Previous int
+	Next     int
+}
+
+// searchRequest reads a search from the query parameters q, category, author and page.
+// A page that is not a number is left at zero, the first page.
+func searchRequest(r *http.Request) models.SearchRequest {
+	query := r.URL.Query()
+	page, _ := strconv.Atoi(query.Get("page"))
+	return models.SearchRequest{
+		Query:    query.Get("q"),
+		Category: query.Get("category"),
+		Author:   query.Get("author"),
+		Page:     page,
+	}
+}
+
+// search shows the search form and, once something was searched for, a page of results.
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/search.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	request := searchRequest(r)
+	page := &searchPage{
+		User:     h.services.Authorization.GetSessionTokenFromRequest(r),
+		Query:    request.Query,
+		Category: request.Category,
+		Author:   request.Author,
+	}
+
+	if request.Query != "" {
+		page.Results, err = h.services.Search(request)
+		if err != nil {
+			h.searchError(w, err)
+			return
+		}
+		page.Previous = page.Results.Page - 1
+		page.Next = page.Results.Page + 1
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// searchError renders the error page matching an error returned by the search service.
+func (h *Handler) searchError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSearch):
+		h.errorPage(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, service.ErrSearchUnavailable):
+		h.errorPage(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// apiSearch serves /api/v1/search.
+func (h *Handler) apiSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	results, err := h.services.Search(searchRequest(r))
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+	if results.Results == nil {
+		results.Results = []models.SearchResult{}
+	}
+
+	h.writeJSON(w, http.StatusOK, results)
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"forum/internal/service.go"
+)
+
+// sessionsPage represents the data needed to render the active sessions page.
+type sessionsPage struct {
+	User     models.User
+	Sessions []models.Session
+}
+
+// getSessions lists the active sessions of the current user.
+func (h *Handler) getSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/sessions.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	sessions, err := h.services.GetUserSessions(user.ID)
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	page := &sessionsPage{
+		User:     user,
+		Sessions: sessions,
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// revokeSession signs the current user out of one of their sessions.
+func (h *Handler) revokeSession(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	sessionID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	sessions, err := h.services.GetUserSessions(user.ID)
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err = h.services.RevokeSession(user.ID, sessionID); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			h.errorPage(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	for _, session := range sessions {
+		if session.ID == sessionID && session.Token == user.Token {
+			// The current session was revoked, so the browser is signed out as well.
+			http.SetCookie(w, &http.Cookie{
+				Name:    "sessionID",
+				Value:   "",
+				Expires: time.Now(),
+			})
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+	}
+
+	http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+}
+
+// clientIP returns the address of the client that sent the request.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"forum/internal/models"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// tagCloudSizes is the number of font sizes that the tag cloud draws tags in.
+const tagCloudSizes = 5
+
+// tagsPage represents the data needed to render the tag cloud.
+type tagsPage struct {
+	User models.User
+	Tags []tagCloudEntry
+}
+
+// tagCloudEntry is a tag of the tag cloud with the size it is drawn in, from 1 to tagCloudSizes.
+type tagCloudEntry struct {
+	models.Tag
+	Size int
+}
+
+// tagCloud shows the tags in use in alphabetical order, the more posts carry a tag the larger it is drawn.
+// Sizes grow with the logarithm of the usage count, so that a few popular tags do not shrink all the others.
+func tagCloud(tags []models.Tag) []tagCloudEntry {
+	most := 1
+	for _, tag := range tags {
+		if tag.Count > most {
+			most = tag.Count
+		}
+	}
+
+	entries := make([]tagCloudEntry, len(tags))
+	for i, tag := range tags {
+		size := 1
+		if most > 1 {
+			size += int(math.Round(math.Log(float64(tag.Count)) / math.Log(float64(most)) * (tagCloudSizes - 1)))
+		}
+		entries[i] = tagCloudEntry{Tag: tag, Size: size}
+	}
+	return entries
+}
+
+// tags shows the tag cloud of the most used tags.
+func (h *Handler) tags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tmpl, err := h.parseTemplate("web/template/tags.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	tags, err := h.services.GetTags("", 0)
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+
+	page := &tagsPage{
+		User: h.services.Authorization.GetSessionTokenFromRequest(r),
+		Tags: tagCloud(tags),
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// apiTagList is the response body of tag listings.
+type apiTagList struct {
+	Tags []models.Tag `json:"tags"`
+}
+
+// apiTags serves /api/v1/tags, the tags in use that start with ?prefix=, the most used first,
+// for suggesting tags while typing. ?limit= caps how many are returned.
+func (h *Handler) apiTags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		h.apiMethodNotAllowed(w)
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 0
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			h.apiErrorResponse(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+
+	tags, err := h.services.GetTags(query.Get("prefix"), limit)
+	if err != nil {
+		h.apiServiceError(w, err)
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	h.writeJSON(w, http.StatusOK, apiTagList{Tags: tags})
+}
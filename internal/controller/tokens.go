@@ -0,0 +1,90 @@
+package controller
+
+import (
+	"errors"
+	"forum/internal/models"
+	"net/http"
+	"strconv"
+
+	"forum/internal/service.go"
+)
+
+// tokensPage represents the data needed to render the access tokens page.
+type tokensPage struct {
+	User         models.User
+	Tokens       []models.AccessToken
+	Scopes       []string
+	NewToken     string
+	ErrorMessage string
+}
+
+// accessTokens lists the access tokens of the current user and creates new ones.
+func (h *Handler) accessTokens(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := h.parseTemplate("web/template/tokens.html")
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+	page := &tokensPage{
+		User:   user,
+		Scopes: service.TokenScopes,
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		r.ParseForm()
+		token, err := h.services.CreateAccessToken(user.ID, r.FormValue("name"), r.Form["scope"])
+		if err != nil {
+			if !errors.Is(err, service.ErrInvalidTokenName) && !errors.Is(err, service.ErrInvalidScope) {
+				h.errorPage(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusBadRequest)
+			page.ErrorMessage = "Give the token a name and at least one scope"
+		}
+		page.NewToken = token.Token
+	default:
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	page.Tokens, err = h.services.GetAccessTokens(user.ID)
+	if err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	if err = tmpl.Execute(w, page); err != nil {
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// revokeAccessToken deletes one of the access tokens of the current user.
+func (h *Handler) revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.errorPage(w, http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed))
+		return
+	}
+
+	tokenID, err := strconv.Atoi(r.FormValue("id"))
+	if err != nil {
+		h.errorPage(w, http.StatusNotFound, http.StatusText(http.StatusNotFound))
+		return
+	}
+
+	user := r.Context().Value(ctxKeyUser).(models.User)
+
+	if err = h.services.RevokeAccessToken(user.ID, tokenID); err != nil {
+		if errors.Is(err, service.ErrAccessTokenNotFound) {
+			h.errorPage(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.errorPage(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	http.Redirect(w, r, "/tokens", http.StatusSeeOther)
+}
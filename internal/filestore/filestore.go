@@ -0,0 +1,103 @@
+// Package filestore keeps the files that users upload, like the attachments of posts, outside of the database.
+//
+// Files are stored under keys that the forum picks, which are slash separated relative paths such as
+// "3f9c0a.png". Store is the interface the rest of the forum uses, Disk keeps the files in a local directory.
+package filestore
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidKey is returned for a key that is not a clean relative path.
+var ErrInvalidKey = errors.New("filestore: invalid key")
+
+// Store keeps files under keys.
+type Store interface {
+	// Save stores the content of r under key, replacing the file stored under it, if any.
+	Save(key string, r io.Reader) error
+	// Open opens the file stored under key. The error satisfies errors.Is(err, fs.ErrNotExist) when there is none.
+	Open(key string) (io.ReadSeekCloser, error)
+	// Delete removes the file stored under key, a key without a file is not an error.
+	Delete(key string) error
+}
+
+// Disk is a Store that keeps files in a directory of the local disk.
+type Disk struct {
+	dir string
+}
+
+// NewDisk returns a Disk that keeps its files in dir, creating the directory when needed.
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// Save writes the file to a temporary name first and renames it once complete,
+// so that a file is never seen half written.
+func (d *Disk) Save(key string, r io.Reader) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err = os.Rename(tmp.Name(), name); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (d *Disk) Open(key string) (io.ReadSeekCloser, error) {
+	name, err := d.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(name)
+}
+
+func (d *Disk) Delete(key string) error {
+	name, err := d.path(key)
+	if err != nil {
+		return err
+	}
+	if err = os.Remove(name); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// path returns the name of the file for key, refusing keys that could reach outside of the directory.
+func (d *Disk) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, `\`) {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,483 @@
+package markdown
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+type blockKind int
+
+const (
+	paragraphBlock blockKind = iota
+	headingBlock
+	codeBlock
+	quoteBlock
+	listBlock
+	itemBlock
+	ruleBlock
+)
+
+// block is a block of a document. Paragraphs and headings hold their text, code blocks their lines,
+// and quotes, lists and list items the blocks they contain.
+type block struct {
+	kind     blockKind
+	text     string
+	lines    []string
+	children []*block
+
+	// level is the level of a heading.
+	level int
+	// language is the first word of the info string of a fenced code block.
+	language string
+	// ordered lists are numbered from start, loose lists keep their paragraphs apart.
+	ordered bool
+	start   int
+	loose   bool
+}
+
+// parseBlocks parses lines, stripped of the markers of the containers they are in, into blocks.
+func parseBlocks(lines []string) []*block {
+	var blocks []*block
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		if isBlank(line) {
+			i++
+			continue
+		}
+
+		if indent(line) >= 4 {
+			code, next := parseIndentedCode(lines, i)
+			blocks = append(blocks, code)
+			i = next
+			continue
+		}
+
+		if fence, ok := openFence(line); ok {
+			code, next := parseFencedCode(lines, i, fence)
+			blocks = append(blocks, code)
+			i = next
+			continue
+		}
+
+		if isRule(line) {
+			blocks = append(blocks, &block{kind: ruleBlock})
+			i++
+			continue
+		}
+
+		if level, text, ok := atxHeading(line); ok {
+			blocks = append(blocks, &block{kind: headingBlock, level: level, text: text})
+			i++
+			continue
+		}
+
+		if isQuote(line) {
+			quote, next := parseQuote(lines, i)
+			blocks = append(blocks, quote)
+			i = next
+			continue
+		}
+
+		if _, ok := listMarker(line); ok {
+			list, next := parseList(lines, i)
+			blocks = append(blocks, list)
+			i = next
+			continue
+		}
+
+		paragraph, next := parseParagraph(lines, i)
+		blocks = append(blocks, paragraph)
+		i = next
+	}
+	return blocks
+}
+
+// isBlank tells whether a line holds nothing but whitespace.
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// indent returns the number of spaces that a line starts with.
+func indent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// unindent removes up to n leading spaces from a line.
+func unindent(line string, n int) string {
+	if spaces := indent(line); spaces < n {
+		n = spaces
+	}
+	return line[n:]
+}
+
+// interrupts tells whether a line starts a block that ends the paragraph before it.
+// Ordered lists only interrupt a paragraph when they start at one, so that a sentence
+// wrapped before a number is not taken for a list.
+func interrupts(line string) bool {
+	if isBlank(line) || isRule(line) || isQuote(line) {
+		return true
+	}
+	if _, ok := openFence(line); ok {
+		return true
+	}
+	if _, _, ok := atxHeading(line); ok {
+		return true
+	}
+	if marker, ok := listMarker(line); ok {
+		return !marker.empty && (!marker.ordered || marker.start == 1)
+	}
+	return false
+}
+
+// parseParagraph reads the paragraph that starts at lines[i], which turns into a heading
+// when it is underlined with = or -.
+func parseParagraph(lines []string, i int) (*block, int) {
+	text := []string{strings.TrimSpace(lines[i])}
+	for i++; i < len(lines); i++ {
+		line := lines[i]
+		if indent(line) < 4 {
+			if level, ok := setextUnderline(line); ok {
+				return &block{kind: headingBlock, level: level, text: strings.Join(text, "\n")}, i + 1
+			}
+		}
+		if indent(line) < 4 && interrupts(line) {
+			break
+		}
+		text = append(text, strings.TrimLeft(line, " "))
+	}
+	return &block{kind: paragraphBlock, text: strings.Join(text, "\n")}, i
+}
+
+// setextUnderline reports the level of the heading that a line of = or - underlines.
+func setextUnderline(line string) (int, bool) {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return 0, false
+	}
+	switch {
+	case strings.Trim(trimmed, "=") == "":
+		return 1, true
+	case strings.Trim(trimmed, "-") == "":
+		return 2, true
+	}
+	return 0, false
+}
+
+// atxHeading reads a heading that starts with one to six #.
+func atxHeading(line string) (int, string, bool) {
+	if indent(line) >= 4 {
+		return 0, "", false
+	}
+	line = strings.TrimLeft(line, " ")
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, "", false
+	}
+
+	text := strings.TrimSpace(line[level:])
+	// A closing sequence of # is dropped when a space comes before it.
+	if closed := strings.TrimRight(text, "#"); closed == "" || strings.HasSuffix(closed, " ") {
+		text = strings.TrimSpace(closed)
+	}
+	return level, text, true
+}
+
+// isRule tells whether a line is a thematic break: three or more *, - or _ and nothing else but spaces.
+func isRule(line string) bool {
+	if indent(line) >= 4 {
+		return false
+	}
+	var marker byte
+	count := 0
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case ' ', '\t':
+		case '*', '-', '_':
+			if marker != 0 && c != marker {
+				return false
+			}
+			marker = c
+			count++
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// fence is the opening line of a fenced code block.
+type fence struct {
+	indent   int
+	marker   byte
+	length   int
+	language string
+}
+
+// openFence reads the opening of a fenced code block: three or more ` or ~ and an optional info string.
+func openFence(line string) (fence, bool) {
+	f := fence{indent: indent(line)}
+	if f.indent >= 4 {
+		return fence{}, false
+	}
+	line = line[f.indent:]
+	if line == "" || (line[0] != '`' && line[0] != '~') {
+		return fence{}, false
+	}
+	f.marker = line[0]
+	for f.length < len(line) && line[f.length] == f.marker {
+		f.length++
+	}
+	if f.length < 3 {
+		return fence{}, false
+	}
+
+	info := strings.TrimSpace(line[f.length:])
+	if f.marker == '`' && strings.Contains(info, "`") {
+		return fence{}, false
+	}
+	if words := strings.Fields(info); len(words) > 0 {
+		f.language = unescapePunctuation(words[0])
+	}
+	return f, true
+}
+
+// closesFence tells whether a line closes a fenced code block.
+func (f fence) closesFence(line string) bool {
+	if indent(line) >= 4 {
+		return false
+	}
+	line = strings.TrimSpace(line)
+	return len(line) >= f.length && strings.Trim(line, string(f.marker)) == ""
+}
+
+// parseFencedCode reads the fenced code block that opens at lines[i], it runs to the end of the lines when it is not closed.
+func parseFencedCode(lines []string, i int, f fence) (*block, int) {
+	code := &block{kind: codeBlock, language: f.language}
+	for i++; i < len(lines); i++ {
+		if f.closesFence(lines[i]) {
+			return code, i + 1
+		}
+		code.lines = append(code.lines, unindent(lines[i], f.indent))
+	}
+	return code, i
+}
+
+// parseIndentedCode reads the code block indented by four spaces that starts at lines[i].
+func parseIndentedCode(lines []string, i int) (*block, int) {
+	code := &block{kind: codeBlock}
+	for ; i < len(lines); i++ {
+		if !isBlank(lines[i]) && indent(lines[i]) < 4 {
+			break
+		}
+		code.lines = append(code.lines, unindent(lines[i], 4))
+	}
+	for len(code.lines) > 0 && isBlank(code.lines[len(code.lines)-1]) {
+		code.lines = code.lines[:len(code.lines)-1]
+	}
+	return code, i
+}
+
+// isQuote tells whether a line starts with the > of a block quote.
+func isQuote(line string) bool {
+	return indent(line) < 4 && strings.HasPrefix(strings.TrimLeft(line, " "), ">")
+}
+
+// parseQuote reads the block quote that starts at lines[i]. Lines without > that carry on a paragraph
+// belong to the quote as well.
+func parseQuote(lines []string, i int) (*block, int) {
+	var inner []string
+	for ; i < len(lines); i++ {
+		line := lines[i]
+		if isQuote(line) {
+			line = strings.TrimLeft(line, " ")[1:]
+			if strings.HasPrefix(line, " ") {
+				line = line[1:]
+			}
+			inner = append(inner, expandIndent(line))
+			continue
+		}
+		if len(inner) == 0 || isBlank(inner[len(inner)-1]) || interrupts(line) {
+			break
+		}
+		inner = append(inner, line)
+	}
+	return &block{kind: quoteBlock, children: parseBlocks(inner)}, i
+}
+
+// marker is the marker that starts a list item.
+type marker struct {
+	ordered bool
+	// char is the bullet of a bullet list or the delimiter after the number of an ordered list.
+	char  byte
+	start int
+	// width is the indent of the content of the item.
+	width int
+	// empty items have nothing after their marker on their first line.
+	empty bool
+}
+
+// listMarker reads the marker of a list item: -, + or * for bullet lists,
+// up to nine digits followed by . or ) for ordered lists.
+func listMarker(line string) (marker, bool) {
+	spaces := indent(line)
+	if spaces >= 4 {
+		return marker{}, false
+	}
+	rest := line[spaces:]
+
+	var m marker
+	switch {
+	case rest != "" && (rest[0] == '-' || rest[0] == '+' || rest[0] == '*'):
+		m.char = rest[0]
+		rest = rest[1:]
+	default:
+		digits := 0
+		for digits < len(rest) && digits < 10 && rest[digits] >= '0' && rest[digits] <= '9' {
+			digits++
+		}
+		if digits == 0 || digits > 9 || digits == len(rest) || (rest[digits] != '.' && rest[digits] != ')') {
+			return marker{}, false
+		}
+		m.ordered = true
+		m.start, _ = strconv.Atoi(rest[:digits])
+		m.char = rest[digits]
+		rest = rest[digits+1:]
+	}
+
+	width := len(line) - len(rest)
+	if isBlank(rest) {
+		m.empty = true
+		m.width = width + 1
+		return m, true
+	}
+	if rest[0] != ' ' {
+		return marker{}, false
+	}
+	gap := indent(rest)
+	if gap > 4 {
+		// The content is an indented code block, which starts one space after the marker.
+		gap = 1
+	}
+	m.width = width + gap
+	return m, true
+}
+
+// isListItem tells whether a line starts a list item.
+func isListItem(line string) bool {
+	_, ok := listMarker(line)
+	return ok
+}
+
+// parseList reads the list that starts at lines[i], its items have markers of the same kind.
+func parseList(lines []string, i int) (*block, int) {
+	first, _ := listMarker(lines[i])
+	list := &block{kind: listBlock, ordered: first.ordered, start: first.start}
+
+	for i < len(lines) {
+		m, ok := listMarker(lines[i])
+		if !ok || m.ordered != first.ordered || m.char != first.char || isRule(lines[i]) {
+			break
+		}
+
+		inner := []string{""}
+		if !m.empty {
+			inner[0] = lines[i][m.width:]
+		}
+		blankBefore := false
+	item:
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			switch {
+			case isBlank(line):
+				inner = append(inner, "")
+				blankBefore = true
+				continue
+			case indent(line) >= m.width:
+				inner = append(inner, line[m.width:])
+			case !blankBefore && !interrupts(line) && !isListItem(line) && !isBlank(inner[len(inner)-1]):
+				// A lazy line carries on the paragraph of the item.
+				inner = append(inner, line)
+			default:
+				break item
+			}
+			if blankBefore {
+				list.loose = true
+			}
+			blankBefore = false
+		}
+		for len(inner) > 0 && isBlank(inner[len(inner)-1]) {
+			inner = inner[:len(inner)-1]
+		}
+		list.children = append(list.children, &block{kind: itemBlock, children: parseBlocks(inner)})
+
+		if blankBefore && i < len(lines) {
+			if next, ok := listMarker(lines[i]); ok && next.ordered == first.ordered && next.char == first.char {
+				list.loose = true
+			}
+		}
+	}
+	return list, i
+}
+
+// renderBlocks writes the HTML of blocks, tight lists leave the paragraphs of their items unwrapped.
+func renderBlocks(out *strings.Builder, blocks []*block, tight bool) {
+	for i, b := range blocks {
+		if tight && i > 0 && blocks[i-1].kind == paragraphBlock {
+			out.WriteString("\n")
+		}
+		switch b.kind {
+		case paragraphBlock:
+			if tight {
+				out.WriteString(renderInline(b.text))
+				continue
+			}
+			out.WriteString("<p>" + renderInline(b.text) + "</p>\n")
+		case headingBlock:
+			tag := "h" + strconv.Itoa(b.level)
+			out.WriteString("<" + tag + ">" + renderInline(b.text) + "</" + tag + ">\n")
+		case codeBlock:
+			out.WriteString("<pre><code")
+			if b.language != "" {
+				out.WriteString(` class="language-` + html.EscapeString(b.language) + `"`)
+			}
+			out.WriteString(">")
+			for _, line := range b.lines {
+				out.WriteString(html.EscapeString(line) + "\n")
+			}
+			out.WriteString("</code></pre>\n")
+		case quoteBlock:
+			out.WriteString("<blockquote>\n")
+			renderBlocks(out, b.children, false)
+			out.WriteString("</blockquote>\n")
+		case listBlock:
+			if b.ordered {
+				if b.start != 1 {
+					out.WriteString(`<ol start="` + strconv.Itoa(b.start) + `">` + "\n")
+				} else {
+					out.WriteString("<ol>\n")
+				}
+			} else {
+				out.WriteString("<ul>\n")
+			}
+			for _, item := range b.children {
+				out.WriteString("<li>")
+				if len(item.children) > 0 && (b.loose || item.children[0].kind != paragraphBlock) {
+					out.WriteString("\n")
+				}
+				renderBlocks(out, item.children, !b.loose)
+				out.WriteString("</li>\n")
+			}
+			if b.ordered {
+				out.WriteString("</ol>\n")
+			} else {
+				out.WriteString("</ul>\n")
+			}
+		case ruleBlock:
+			out.WriteString("<hr />\n")
+		}
+	}
+}
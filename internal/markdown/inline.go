@@ -0,0 +1,533 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// asciiPunctuation are the characters that a backslash escapes.
+const asciiPunctuation = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+
+var (
+	// entity matches a named or numeric character reference.
+	entity = regexp.MustCompile(`^&(?:#[0-9]{1,7}|#[xX][0-9a-fA-F]{1,6}|[A-Za-z][A-Za-z0-9]{1,31});`)
+	// uriAutolink and emailAutolink match the autolinks between < and >.
+	uriAutolink   = regexp.MustCompile(`^<([A-Za-z][A-Za-z0-9+.-]{1,31}:[^\s<>]*)>`)
+	emailAutolink = regexp.MustCompile(`^<([A-Za-z0-9.!#$%&'*+/=?^_{|}~-]+@[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]{0,61}[A-Za-z0-9])?)*)>`)
+)
+
+// node is a piece of the inline HTML: markup, or a run of * or _ that may still open or close emphasis.
+type node struct {
+	html string
+	// delim is the character of a delimiter run, count how many of them are left and length how many there were.
+	delim             byte
+	count, length     int
+	canOpen, canClose bool
+	prev, next        *node
+}
+
+// bracket is a [ or ![ that a ] may close into a link or an image.
+type bracket struct {
+	node  *node
+	image bool
+	// active is cleared for the brackets before a link, links do not nest.
+	active bool
+}
+
+// inlineParser turns the text of a paragraph or heading into HTML.
+type inlineParser struct {
+	src string
+	pos int
+	// text is the literal text read since the last node.
+	text       []byte
+	head, tail *node
+	brackets   []*bracket
+}
+
+// renderInline renders the inlines of a paragraph or heading.
+func renderInline(text string) string {
+	p := &inlineParser{src: strings.TrimSpace(text)}
+	p.parse()
+	p.processEmphasis(nil)
+	return render(p.head, nil)
+}
+
+func (p *inlineParser) parse() {
+	for p.pos < len(p.src) {
+		switch c := p.src[p.pos]; c {
+		case '\\':
+			p.backslash()
+		case '`':
+			p.codeSpan()
+		case '*', '_':
+			p.delimiterRun(c)
+		case '[':
+			p.openBracket(false)
+		case '!':
+			if strings.HasPrefix(p.src[p.pos:], "![") {
+				p.openBracket(true)
+			} else {
+				p.literal(1)
+			}
+		case ']':
+			p.closeBracket()
+		case '<':
+			p.autolink()
+		case '&':
+			p.entity()
+		case '\n':
+			p.lineBreak()
+		case 'h':
+			p.bareURL()
+		default:
+			p.literal(1)
+		}
+	}
+	p.flush()
+}
+
+// literal adds the next n bytes of the source to the text.
+func (p *inlineParser) literal(n int) {
+	p.text = append(p.text, p.src[p.pos:p.pos+n]...)
+	p.pos += n
+}
+
+// flush turns the text read so far into a node.
+func (p *inlineParser) flush() {
+	if len(p.text) > 0 {
+		p.append(&node{html: html.EscapeString(string(p.text))})
+		p.text = p.text[:0]
+	}
+}
+
+// append adds a node after the last one.
+func (p *inlineParser) append(n *node) {
+	n.prev = p.tail
+	if p.tail == nil {
+		p.head = n
+	} else {
+		p.tail.next = n
+	}
+	p.tail = n
+}
+
+// insertAfter adds n right after at.
+func (p *inlineParser) insertAfter(at, n *node) {
+	n.prev, n.next = at, at.next
+	if at.next == nil {
+		p.tail = n
+	} else {
+		at.next.prev = n
+	}
+	at.next = n
+}
+
+// insertBefore adds n right before at.
+func (p *inlineParser) insertBefore(at, n *node) {
+	n.prev, n.next = at.prev, at
+	if at.prev == nil {
+		p.head = n
+	} else {
+		at.prev.next = n
+	}
+	at.prev = n
+}
+
+// backslash escapes the punctuation after it, or breaks the line when it ends one.
+func (p *inlineParser) backslash() {
+	if p.pos+1 < len(p.src) {
+		next := p.src[p.pos+1]
+		if next == '\n' {
+			p.flush()
+			p.append(&node{html: "<br />\n"})
+			p.pos += 2
+			p.skipSpaces()
+			return
+		}
+		if strings.IndexByte(asciiPunctuation, next) >= 0 {
+			p.pos++
+			p.literal(1)
+			return
+		}
+	}
+	p.literal(1)
+}
+
+// codeSpan reads code between runs of backticks of the same length.
+func (p *inlineParser) codeSpan() {
+	n := runLength(p.src, p.pos, '`')
+	for end := p.pos + n; end < len(p.src); {
+		start := strings.IndexByte(p.src[end:], '`')
+		if start < 0 {
+			break
+		}
+		start += end
+		closing := runLength(p.src, start, '`')
+		if closing != n {
+			end = start + closing
+			continue
+		}
+
+		code := strings.ReplaceAll(p.src[p.pos+n:start], "\n", " ")
+		if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+			code = code[1 : len(code)-1]
+		}
+		p.flush()
+		p.append(&node{html: "<code>" + html.EscapeString(code) + "</code>"})
+		p.pos = start + n
+		return
+	}
+	p.literal(n)
+}
+
+// runLength counts the c that follow one another from position i of s.
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// delimiterRun reads a run of * or _, whether it can open or close emphasis depends on what is around it.
+func (p *inlineParser) delimiterRun(c byte) {
+	n := runLength(p.src, p.pos, c)
+	before, after := ' ', ' '
+	if p.pos > 0 {
+		before, _ = utf8.DecodeLastRuneInString(p.src[:p.pos])
+	}
+	if p.pos+n < len(p.src) {
+		after, _ = utf8.DecodeRuneInString(p.src[p.pos+n:])
+	}
+
+	leftFlanking := !unicode.IsSpace(after) && (!isPunctuation(after) || unicode.IsSpace(before) || isPunctuation(before))
+	rightFlanking := !unicode.IsSpace(before) && (!isPunctuation(before) || unicode.IsSpace(after) || isPunctuation(after))
+
+	run := &node{delim: c, count: n, length: n, canOpen: leftFlanking, canClose: rightFlanking}
+	if c == '_' {
+		// An underscore inside a word, as in snake_case, does not emphasize.
+		run.canOpen = leftFlanking && (!rightFlanking || isPunctuation(before))
+		run.canClose = rightFlanking && (!leftFlanking || isPunctuation(after))
+	}
+
+	p.flush()
+	p.append(run)
+	p.pos += n
+}
+
+// isPunctuation tells whether r is a punctuation character or a symbol.
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
+
+// processEmphasis matches the delimiter runs after bottom into emphasis, nil processes every run.
+func (p *inlineParser) processEmphasis(bottom *node) {
+	first := p.head
+	if bottom != nil {
+		first = bottom.next
+	}
+
+	for closer := first; closer != nil; closer = closer.next {
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			var opener *node
+			for n := closer.prev; n != nil && n != bottom; n = n.prev {
+				if n.delim == closer.delim && n.canOpen && n.count > 0 && !oddMatch(n, closer) {
+					opener = n
+					break
+				}
+			}
+			if opener == nil {
+				break
+			}
+
+			use, tag := 1, "em"
+			if opener.count >= 2 && closer.count >= 2 {
+				use, tag = 2, "strong"
+			}
+			opener.count -= use
+			closer.count -= use
+			p.insertAfter(opener, &node{html: "<" + tag + ">"})
+			p.insertBefore(closer, &node{html: "</" + tag + ">"})
+
+			// The runs between the two can no longer match.
+			for n := opener.next; n != closer; n = n.next {
+				n.canOpen, n.canClose = false, false
+			}
+		}
+	}
+}
+
+// oddMatch applies the rule of three: a run that can both open and close does not match another run
+// when their lengths add up to a multiple of three, unless both lengths are.
+func oddMatch(opener, closer *node) bool {
+	return (opener.canClose || closer.canOpen) &&
+		(opener.length+closer.length)%3 == 0 &&
+		!(opener.length%3 == 0 && closer.length%3 == 0)
+}
+
+// render returns the HTML of the nodes from first up to stop, delimiter runs left unmatched are literal.
+func render(first, stop *node) string {
+	var out strings.Builder
+	for n := first; n != stop; n = n.next {
+		if n.delim != 0 {
+			out.WriteString(strings.Repeat(string(n.delim), n.count))
+			continue
+		}
+		out.WriteString(n.html)
+	}
+	return out.String()
+}
+
+// openBracket reads a [ or ![ that may start the text of a link or an image.
+func (p *inlineParser) openBracket(image bool) {
+	n := 1
+	if image {
+		n = 2
+	}
+	p.flush()
+	opener := &node{html: p.src[p.pos : p.pos+n]}
+	p.append(opener)
+	p.brackets = append(p.brackets, &bracket{node: opener, image: image, active: true})
+	p.pos += n
+}
+
+// closeBracket reads a ], which makes a link or an image of the text since the last [ when
+// a destination in parentheses follows it.
+func (p *inlineParser) closeBracket() {
+	if len(p.brackets) == 0 {
+		p.literal(1)
+		return
+	}
+	opener := p.brackets[len(p.brackets)-1]
+	p.brackets = p.brackets[:len(p.brackets)-1]
+
+	destination, title, end, ok := linkTail(p.src, p.pos+1)
+	if !opener.active || !ok {
+		p.literal(1)
+		return
+	}
+
+	p.flush()
+	p.processEmphasis(opener.node)
+	text := render(opener.node.next, nil)
+	opener.node.next = nil
+	p.tail = opener.node
+
+	if title != "" {
+		title = ` title="` + html.EscapeString(title) + `"`
+	}
+	switch {
+	case opener.image && safeURL(destination):
+		opener.node.html = `<img src="` + escapeURL(destination) + `" alt="` + stripTags(text) + `"` + title + ` />`
+	case opener.image:
+		opener.node.html = stripTags(text)
+	case safeURL(destination):
+		opener.node.html = `<a href="` + escapeURL(destination) + `" rel="nofollow"` + title + `>` + text + `</a>`
+	default:
+		opener.node.html = text
+	}
+
+	if !opener.image {
+		for _, earlier := range p.brackets {
+			if !earlier.image {
+				earlier.active = false
+			}
+		}
+	}
+	p.pos = end
+}
+
+// linkTail reads the destination and optional title in parentheses that follow the text of an inline link,
+// src[i] being the character after the ]. It returns the position after the closing parenthesis.
+func linkTail(src string, i int) (destination, title string, end int, ok bool) {
+	if i >= len(src) || src[i] != '(' {
+		return "", "", 0, false
+	}
+	i = skipWhitespace(src, i+1)
+
+	if i < len(src) && src[i] == '<' {
+		close := strings.IndexAny(src[i+1:], "<>\n")
+		if close < 0 || src[i+1+close] != '>' {
+			return "", "", 0, false
+		}
+		destination = src[i+1 : i+1+close]
+		i += close + 2
+	} else {
+		start, depth := i, 0
+	scan:
+		for ; i < len(src); i++ {
+			switch c := src[i]; {
+			case c == '\\' && i+1 < len(src) && strings.IndexByte(asciiPunctuation, src[i+1]) >= 0:
+				i++
+			case c == '(':
+				depth++
+			case c == ')':
+				if depth == 0 {
+					break scan
+				}
+				depth--
+			case c <= ' ':
+				break scan
+			}
+		}
+		destination = src[start:i]
+	}
+
+	afterDestination := i
+	i = skipWhitespace(src, i)
+	if i < len(src) && i > afterDestination && (src[i] == '"' || src[i] == '\'' || src[i] == '(') {
+		closing := src[i]
+		if closing == '(' {
+			closing = ')'
+		}
+		j := i + 1
+		for ; j < len(src) && src[j] != closing; j++ {
+			if src[j] == '\\' {
+				j++
+			}
+		}
+		if j >= len(src) {
+			return "", "", 0, false
+		}
+		title = src[i+1 : j]
+		i = skipWhitespace(src, j+1)
+	}
+
+	if i >= len(src) || src[i] != ')' {
+		return "", "", 0, false
+	}
+	return unescapePunctuation(destination), unescapePunctuation(title), i + 1, true
+}
+
+// skipWhitespace returns the position of the first character from i on that is not a space, tab or line ending.
+func skipWhitespace(src string, i int) int {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n') {
+		i++
+	}
+	return i
+}
+
+// unescapePunctuation removes the backslashes that escape punctuation.
+func unescapePunctuation(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(asciiPunctuation, s[i+1]) >= 0 {
+			i++
+		}
+		out.WriteByte(s[i])
+	}
+	return out.String()
+}
+
+// stripTags removes the markup from HTML and keeps its escaped text, for the alternative text of images.
+func stripTags(s string) string {
+	var out strings.Builder
+	inTag := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '<':
+			inTag = true
+		case s[i] == '>':
+			inTag = false
+		case !inTag:
+			out.WriteByte(s[i])
+		}
+	}
+	return strings.ReplaceAll(out.String(), `"`, "&#34;")
+}
+
+// autolink reads a URL or email address between < and >. Anything else, HTML included, stays as typed.
+func (p *inlineParser) autolink() {
+	rest := p.src[p.pos:]
+	if len(p.brackets) == 0 {
+		if m := uriAutolink.FindStringSubmatch(rest); m != nil && safeURL(m[1]) {
+			p.flush()
+			p.append(&node{html: `<a href="` + escapeURL(m[1]) + `" rel="nofollow">` + html.EscapeString(m[1]) + `</a>`})
+			p.pos += len(m[0])
+			return
+		}
+		if m := emailAutolink.FindStringSubmatch(rest); m != nil {
+			p.flush()
+			p.append(&node{html: `<a href="mailto:` + escapeURL(m[1]) + `">` + html.EscapeString(m[1]) + `</a>`})
+			p.pos += len(m[0])
+			return
+		}
+	}
+	p.literal(1)
+}
+
+// entity decodes a character reference, the decoded character is escaped again like any other text.
+func (p *inlineParser) entity() {
+	if m := entity.FindString(p.src[p.pos:]); m != "" {
+		if decoded := html.UnescapeString(m); decoded != m {
+			p.text = append(p.text, decoded...)
+			p.pos += len(m)
+			return
+		}
+	}
+	p.literal(1)
+}
+
+// lineBreak ends a line, with a hard break when two or more spaces come before it.
+func (p *inlineParser) lineBreak() {
+	trimmed := strings.TrimRight(string(p.text), " ")
+	hard := len(p.text)-len(trimmed) >= 2
+	p.text = append(p.text[:0], trimmed...)
+	p.flush()
+	if hard {
+		p.append(&node{html: "<br />\n"})
+	} else {
+		p.append(&node{html: "\n"})
+	}
+	p.pos++
+	p.skipSpaces()
+}
+
+// skipSpaces skips the spaces that start a line.
+func (p *inlineParser) skipSpaces() {
+	for p.pos < len(p.src) && p.src[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// bareURL links a URL starting with http:// or https:// that is written without < and >.
+// Punctuation that ends the sentence after the URL is left out of it.
+func (p *inlineParser) bareURL() {
+	rest := p.src[p.pos:]
+	if len(p.brackets) > 0 || !(strings.HasPrefix(rest, "http://") || strings.HasPrefix(rest, "https://")) {
+		p.literal(1)
+		return
+	}
+	if p.pos > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(p.src[:p.pos]); !unicode.IsSpace(before) && !strings.ContainsRune("(*_~", before) {
+			p.literal(1)
+			return
+		}
+	}
+
+	end := strings.IndexFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == '<' })
+	if end < 0 {
+		end = len(rest)
+	}
+	url := strings.TrimRight(rest[:end], "?!.,:*_~'\"")
+	for strings.HasSuffix(url, ")") && strings.Count(url, "(") < strings.Count(url, ")") {
+		url = strings.TrimRight(url[:len(url)-1], "?!.,:*_~'\"")
+	}
+	if host := url[strings.Index(url, "//")+2:]; host == "" || host[0] == '/' {
+		p.literal(1)
+		return
+	}
+
+	p.flush()
+	p.append(&node{html: `<a href="` + escapeURL(url) + `" rel="nofollow">` + html.EscapeString(url) + `</a>`})
+	p.pos += len(url)
+}
@@ -0,0 +1,85 @@
+// Package markdown renders the CommonMark that posts and comments are written in to HTML.
+//
+// It covers the blocks and inlines that people use to share code: paragraphs, headings, fenced and
+// indented code blocks, block quotes, lists, thematic breaks, emphasis, code spans, links, images and
+// autolinks. Reference links are not supported and stay as typed.
+//
+// The HTML is safe to put in a page as is: raw HTML is shown as typed rather than passed through,
+// and links and images only keep URLs that are relative or use the http, https or mailto schemes.
+package markdown
+
+import (
+	"html"
+	"html/template"
+	"strings"
+)
+
+// Render renders markdown source to sanitized HTML.
+func Render(source string) template.HTML {
+	var out strings.Builder
+	renderBlocks(&out, parseBlocks(splitLines(source)), false)
+	return template.HTML(out.String())
+}
+
+// splitLines splits source into lines, turning the tabs that indent a line into spaces at tab stops of four.
+func splitLines(source string) []string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	source = strings.ReplaceAll(source, "\r", "\n")
+	source = strings.ReplaceAll(source, "\x00", "�")
+	lines := strings.Split(strings.TrimRight(source, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = expandIndent(line)
+	}
+	return lines
+}
+
+// expandIndent turns the tabs among the leading whitespace of a line into spaces.
+func expandIndent(line string) string {
+	column := 0
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case ' ':
+			column++
+		case '\t':
+			column += 4 - column%4
+		default:
+			if column == i {
+				return line
+			}
+			return strings.Repeat(" ", column) + line[i:]
+		}
+	}
+	return ""
+}
+
+// safeSchemes are the URL schemes that links and images may use, URLs without a scheme are relative and kept too.
+var safeSchemes = []string{"http", "https", "mailto"}
+
+// safeURL reports whether a link destination may be put in a page.
+// Browsers ignore whitespace and control characters in a scheme, so they are ignored when looking for it.
+func safeURL(url string) bool {
+	var scheme strings.Builder
+	for _, char := range url {
+		switch {
+		case char <= ' ' || char == 0x7f:
+			continue
+		case char == ':':
+			name := strings.ToLower(scheme.String())
+			for _, safe := range safeSchemes {
+				if name == safe {
+					return true
+				}
+			}
+			return false
+		case char == '/' || char == '?' || char == '#':
+			return true
+		}
+		scheme.WriteRune(char)
+	}
+	return true
+}
+
+// escapeURL escapes a link destination for an HTML attribute, spaces are percent encoded.
+func escapeURL(url string) string {
+	return html.EscapeString(strings.ReplaceAll(url, " ", "%20"))
+}
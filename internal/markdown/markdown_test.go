@@ -0,0 +1,134 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// renderTests pair markdown with the HTML it renders to. The sections follow the ways user content could
+// carry markup or script into a page.
+var renderTests = []struct {
+	name   string
+	source string
+	want   string
+}{
+	// Raw HTML is shown as typed.
+	{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+	{"inline tag with handler", `hi <b onclick="x">bold</b>`, "<p>hi &lt;b onclick=&#34;x&#34;&gt;bold&lt;/b&gt;</p>\n"},
+	{"block tag", "<div>\nblock\n</div>", "<p>&lt;div&gt;\nblock\n&lt;/div&gt;</p>\n"},
+	{"tag in heading and quote", "# <h1>\n> <q>quote", "<h1>&lt;h1&gt;</h1>\n<blockquote>\n<p>&lt;q&gt;quote</p>\n</blockquote>\n"},
+	{"img tag", `<img src=x onerror=alert(1)>`, "<p>&lt;img src=x onerror=alert(1)&gt;</p>\n"},
+
+	// Links keep relative, http, https and mailto URLs and drop the others, leaving their text.
+	{"https link", "[x](https://example.com/a?b=1&c=2)", `<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow">x</a></p>` + "\n"},
+	{"relative link", "[x](/relative/path)", `<p><a href="/relative/path" rel="nofollow">x</a></p>` + "\n"},
+	{"mailto link", "[x](mailto:a@b.c)", `<p><a href="mailto:a@b.c" rel="nofollow">x</a></p>` + "\n"},
+	{"javascript link", "[x](javascript:alert(1))", "<p>x</p>\n"},
+	{"mixed case javascript link", "[x](JaVaScRiPt:alert(1))", "<p>x</p>\n"},
+	{"javascript link after a space", "[x]( javascript:alert(1))", "<p>x</p>\n"},
+	{"javascript link with a tab", "[x](<java\tscript:alert(1)>)", "<p>x</p>\n"},
+	{"javascript link with a control character", "[x](<\x01javascript:alert(1)>)", "<p>x</p>\n"},
+	{"javascript link with a newline", "[x](<java\nscript:alert(1)>)", "<p>[x](&lt;java\nscript:alert(1)&gt;)</p>\n"},
+	{"entity encoded javascript link", "[x](jav&#x61;script:alert(1))", `<p><a href="jav&amp;#x61;script:alert(1)" rel="nofollow">x</a></p>` + "\n"},
+	{"entity encoded first letter", "[x](&#106;avascript:alert(1))", `<p><a href="&amp;#106;avascript:alert(1)" rel="nofollow">x</a></p>` + "\n"},
+	{"entity encoded tab", "[x](java&#9;script:alert(1))", `<p><a href="java&amp;#9;script:alert(1)" rel="nofollow">x</a></p>` + "\n"},
+	{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>\n"},
+	{"upper case data link", "[x](DATA:text/html,x)", "<p>x</p>\n"},
+	{"vbscript link", "[x](vbscript:msgbox)", "<p>x</p>\n"},
+	{"quote in destination", `[x]("onmouseover=alert(1))`, `<p><a href="&#34;onmouseover=alert(1)" rel="nofollow">x</a></p>` + "\n"},
+	{"quote in title", `[x](https://e.com "t\" onmouseover=\"alert(1)")`, `<p><a href="https://e.com" rel="nofollow" title="t&#34; onmouseover=&#34;alert(1)">x</a></p>` + "\n"},
+
+	// Images follow the same rules for their src, and an image with an unsafe src is left as its alt text.
+	{"https image", `![alt](https://e.com/i.png "title")`, `<p><img src="https://e.com/i.png" alt="alt" title="title" /></p>` + "\n"},
+	{"quote in alt", `![a"b](https://e.com/i.png)`, `<p><img src="https://e.com/i.png" alt="a&#34;b" /></p>` + "\n"},
+	{"javascript image", "![img](javascript:alert(1))", "<p>img</p>\n"},
+	{"mixed case javascript image", "![img](jAvAsCrIpT:alert(1))", "<p>img</p>\n"},
+	{"data image", "![img](data:image/png;base64,AAAA)", "<p>img</p>\n"},
+	{"data svg image", "![img](data:image/svg+xml,<svg onload=alert(1)>)", "<p>![img](data:image/svg+xml,&lt;svg onload=alert(1)&gt;)</p>\n"},
+
+	// Autolinks.
+	{"url autolink", "<https://example.com>", `<p><a href="https://example.com" rel="nofollow">https://example.com</a></p>` + "\n"},
+	{"mailto autolink", "<mailto:a@b.c>", `<p><a href="mailto:a@b.c" rel="nofollow">mailto:a@b.c</a></p>` + "\n"},
+	{"email autolink", "<a@b.com>", `<p><a href="mailto:a@b.com">a@b.com</a></p>` + "\n"},
+	{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>\n"},
+	{"bare url", "see https://example.com/x.", `<p>see <a href="https://example.com/x" rel="nofollow">https://example.com/x</a>.</p>` + "\n"},
+
+	// Emphasis.
+	{"nested emphasis", "***both*** and *a **b** c* and **a *b* c**", "<p><em><strong>both</strong></em> and <em>a <strong>b</strong> c</em> and <strong>a <em>b</em> c</strong></p>\n"},
+	{"underscores and unclosed", "_a_ __b__ *unclosed", "<p><em>a</em> <strong>b</strong> *unclosed</p>\n"},
+	{"emphasis around a tag", "*<i>x</i>*", "<p><em>&lt;i&gt;x&lt;/i&gt;</em></p>\n"},
+
+	// Code keeps its text, escaped.
+	{"code span", "`a < b && c > d`", "<p><code>a &lt; b &amp;&amp; c &gt; d</code></p>\n"},
+	{"code span with a backtick", "`` a ` <b> ``", "<p><code>a ` &lt;b&gt;</code></p>\n"},
+	{"code span with a tag", "`<script>`", "<p><code>&lt;script&gt;</code></p>\n"},
+	{"fenced code", "```go\nif a < b {}\n```", `<pre><code class="language-go">if a &lt; b {}` + "\n</code></pre>\n"},
+	{"quote in fence language", "```\"><script>\nx\n```", `<pre><code class="language-&#34;&gt;&lt;script&gt;">x` + "\n</code></pre>\n"},
+	{"indented code", "    <indented>", "<pre><code>&lt;indented&gt;\n</code></pre>\n"},
+}
+
+func TestRender(t *testing.T) {
+	for _, tt := range renderTests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Render(tt.source)); got != tt.want {
+				t.Errorf("Render(%q)\n got %q\nwant %q", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+// urlAttribute matches the attributes of the rendered HTML that hold a URL.
+var urlAttribute = regexp.MustCompile(`(?:href|src)="([^"]*)"`)
+
+// TestRenderURLSchemes checks every URL of every rendered test the way a browser would read it: with character
+// references decoded and whitespace and control characters left out, none of them may use an unsafe scheme.
+func TestRenderURLSchemes(t *testing.T) {
+	for _, tt := range renderTests {
+		for _, m := range urlAttribute.FindAllStringSubmatch(string(Render(tt.source)), -1) {
+			url := strings.Map(func(r rune) rune {
+				if r <= ' ' || r == 0x7f {
+					return -1
+				}
+				return r
+			}, html.UnescapeString(m[1]))
+			url = strings.ToLower(url)
+			for _, scheme := range []string{"javascript:", "data:", "vbscript:"} {
+				if strings.HasPrefix(url, scheme) {
+					t.Errorf("%s: Render(%q) has the URL %q", tt.name, tt.source, m[1])
+				}
+			}
+		}
+	}
+}
+
+func TestSafeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://example.com", true},
+		{"HTTP://example.com", true},
+		{"mailto:a@b.c", true},
+		{"/path", true},
+		{"path/to:file", true},
+		{"?q=a:b", true},
+		{"#a:b", true},
+		{"", true},
+		{"javascript:alert(1)", false},
+		{"JAVASCRIPT:alert(1)", false},
+		{" javascript:alert(1)", false},
+		{"java\tscript:alert(1)", false},
+		{"java\x00script:alert(1)", false},
+		{"\x7fjavascript:alert(1)", false},
+		{"data:text/html,x", false},
+		{"vbscript:x", false},
+		{"ftp://example.com", false},
+	}
+	for _, tt := range tests {
+		if got := safeURL(tt.url); got != tt.want {
+			t.Errorf("safeURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Attachment is a file uploaded with a post. Images get a thumbnail, which other files lack.
+// The files themselves are kept by a filestore.Store under FileKey and ThumbnailKey.
+type Attachment struct {
+	ID           int       `json:"id"`
+	PostID       int       `json:"postId"`
+	Name         string    `json:"name"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	FileKey      string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	CreatedAt    Timestamp `json:"createdAt"`
+}
+
+// Upload is a file as sent by a user, before it is checked and stored as an Attachment.
+type Upload struct {
+	Name string
+	Data []byte
+}
+
+// IsImage tells whether the attachment is an image that pages can show.
+func (a Attachment) IsImage() bool {
+	return strings.HasPrefix(a.ContentType, "image/")
+}
+
+// URL is where the attachment can be downloaded.
+func (a Attachment) URL() string {
+	return fmt.Sprintf("/attachments/%d", a.ID)
+}
+
+// ThumbnailURL is where the thumbnail of an image can be downloaded.
+func (a Attachment) ThumbnailURL() string {
+	return fmt.Sprintf("/attachments/%d/thumbnail", a.ID)
+}
+
+// HumanSize formats the size of the attachment in bytes, kilobytes or megabytes.
+func (a Attachment) HumanSize() string {
+	switch {
+	case a.Size < 1<<10:
+		return fmt.Sprintf("%d B", a.Size)
+	case a.Size < 1<<20:
+		return fmt.Sprintf("%.0f KB", float64(a.Size)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(a.Size)/(1<<20))
+	}
+}
@@ -0,0 +1,35 @@
+package models
+
+import "strings"
+
+// Category is a subforum that posts are filed under.
+type Category struct {
+	ID int `json:"id"`
+	// Slug names the category in URLs, it does not change when the category is renamed.
+	Slug        string `json:"slug"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Position orders the categories in the navigation, the lowest first.
+	Position int `json:"position"`
+	// Archived categories keep their posts but take no new ones and are left out of the navigation.
+	Archived bool `json:"archived"`
+}
+
+// CategorySlug derives a slug from the name of a category: its letters and digits in lower case,
+// with a hyphen for every run of other characters between them.
+func CategorySlug(name string) string {
+	var slug strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+	return slug.String()
+}
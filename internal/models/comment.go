@@ -1,10 +1,31 @@
 package models
 
+import "time"
+
 type Comment struct {
-	ID       int
-	PostID   int
-	Author   string
-	Text     string
-	Likes    int
-	DisLikes int
+	ID        int       `json:"id"`
+	PostID    int       `json:"postId"`
+	ParentID  int       `json:"parentId"`
+	Depth     int       `json:"depth,omitempty"`
+	Author    string    `json:"author"`
+	Text      string    `json:"text"`
+	Likes     int       `json:"likes"`
+	DisLikes  int       `json:"dislikes"`
+	Hidden    bool      `json:"hidden"`
+	Deleted   bool      `json:"deleted"`
+	Edited    bool      `json:"edited"`
+	CreatedAt Timestamp `json:"createdAt"`
+	UpdatedAt Timestamp `json:"updatedAt"`
+	// Reactions are only loaded when showing the comments of a post.
+	Reactions []Reaction `json:"reactions,omitempty"`
+}
+
+// CommentRevision is the text a comment had before one of its edits.
+type CommentRevision struct {
+	ID        int       `json:"id"`
+	CommentID int       `json:"commentId"`
+	Text      string    `json:"text"`
+	EditorID  int       `json:"editorId"`
+	Editor    string    `json:"editor"`
+	EditedAt  time.Time `json:"editedAt"`
 }
@@ -0,0 +1,16 @@
+package models
+
+// DiffOp tells how a line differs between two texts.
+type DiffOp string
+
+const (
+	DiffEqual  DiffOp = "equal"
+	DiffInsert DiffOp = "insert"
+	DiffDelete DiffOp = "delete"
+)
+
+// DiffLine is a line of a line-level diff between two texts.
+type DiffLine struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
@@ -0,0 +1,47 @@
+package models
+
+// PageRequest asks for one page of a post listing.
+type PageRequest struct {
+	Sort PostSort
+	// Cursor is the Next of the previous page, empty for the first page.
+	Cursor string
+	// Limit is the number of posts on the page, zero means the default page size.
+	Limit int
+}
+
+// PostPage is one page of a post listing.
+type PostPage struct {
+	Posts []Post `json:"posts"`
+	// Next is the cursor of the following page, empty on the last page.
+	Next string `json:"next,omitempty"`
+}
+
+// PostListing tells the repository which page of a post listing to return.
+type PostListing struct {
+	Sort PostSort
+	// Now is the unix time that the hot ranking ages posts against,
+	// it stays the same while paging so that posts do not move between pages.
+	Now int64
+	// After is the position of the last post of the previous page, nil for the first page.
+	After *PostKey
+	Limit int
+}
+
+// PostKey is the position of a post in a listing: the values of its sort keys and its ID.
+type PostKey struct {
+	Values []float64 `json:"v,omitempty"`
+	ID     int       `json:"id"`
+}
+
+// PostFilter narrows a post listing down, the zero filter keeps every post.
+type PostFilter struct {
+	// CategoryIDs keeps the posts filed under any of the categories, or under all of them when MatchAll is set.
+	CategoryIDs []int
+	MatchAll    bool
+	// CreatedBy keeps the posts written by the user with this ID, zero keeps posts by anyone.
+	CreatedBy int
+	// LikedBy keeps the posts liked by the user with this username, empty keeps posts liked or not.
+	LikedBy string
+	// Tags keeps the posts that carry every one of the tags.
+	Tags []string
+}
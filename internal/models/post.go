@@ -1,17 +1,95 @@
 package models
 
+import "time"
+
 type Post struct {
-	Id       int
-	UserID   int
-	Category []string
-	Title    string
-	Content  string
-	About    string
-	Comments int
-	Like     int
-	DisLike  int
+	Id        int       `json:"id"`
+	UserID    int       `json:"userId"`
+	Category  []string  `json:"categories"`
+	Tags      []string  `json:"tags"`
+	Title     string    `json:"title"`
+	Content   string    `json:"content"`
+	About     string    `json:"about"`
+	Comments  int       `json:"comments"`
+	Like      int       `json:"likes"`
+	DisLike   int       `json:"dislikes"`
+	Hidden    bool      `json:"-"`
+	Edited    bool      `json:"edited"`
+	CreatedAt Timestamp `json:"createdAt"`
+	UpdatedAt Timestamp `json:"updatedAt"`
+	// Attachments are only loaded for a single post, not for listings.
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// Reactions are only loaded when showing a single post.
+	Reactions []Reaction `json:"reactions,omitempty"`
+}
+
+// PostRevision is one version of the title and content of a post.
+// Stored revisions are earlier versions and EditedAt is when they were replaced,
+// the current version of a post has no ID and no EditedAt.
+type PostRevision struct {
+	Version  int        `json:"version"`
+	ID       int        `json:"-"`
+	PostID   int        `json:"postId"`
+	Title    string     `json:"title"`
+	Content  string     `json:"content"`
+	EditorID int        `json:"editorId,omitempty"`
+	Editor   string     `json:"editor,omitempty"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+}
+
+// Current tells whether the revision is the current version of its post.
+func (r PostRevision) Current() bool {
+	return r.ID == 0
+}
+
+// PostSort is the order in which post listings are returned.
+type PostSort string
+
+const (
+	SortNew           PostSort = "new"
+	SortTop           PostSort = "top"
+	SortDiscussed     PostSort = "discussed"
+	SortControversial PostSort = "controversial"
+	SortHot           PostSort = "hot"
+)
+
+// PostSorts lists every sort order, the default one first.
+var PostSorts = []PostSort{SortNew, SortTop, SortDiscussed, SortControversial, SortHot}
+
+// IsValid reports whether s is one of the known sort orders.
+func (s PostSort) IsValid() bool {
+	for _, sort := range PostSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// sortLabels are the names of the sort orders shown to users.
+var sortLabels = map[PostSort]string{
+	SortNew:           "Newest",
+	SortTop:           "Most liked",
+	SortDiscussed:     "Most discussed",
+	SortControversial: "Controversial",
+	SortHot:           "Hot",
+}
+
+// Label is the name of the sort order shown to users.
+func (s PostSort) Label() string {
+	return sortLabels[s]
 }
 
 func NewPost(id, like, dislike, userID, comments int, title, content, about string, category []string) *Post {
-	return &Post{id, userID, category, title, content, about, comments, like, dislike}
+	return &Post{
+		Id:       id,
+		UserID:   userID,
+		Category: category,
+		Title:    title,
+		Content:  content,
+		About:    about,
+		Comments: comments,
+		Like:     like,
+		DisLike:  dislike,
+	}
 }
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ReactionLike and ReactionDislike are the reactions that like and dislike a post or a comment.
+	// A user gives at most one of the two, and both are also counted on the post or comment itself.
+	ReactionLike    = "👍"
+	ReactionDislike = "👎"
+)
+
+// Reaction is how many users reacted to a post or a comment with one emoji.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+	// Users are the first users who reacted, Count tells how many more there are.
+	Users []string `json:"users"`
+	// Reacted tells whether the user reading the reaction is one of them.
+	Reacted bool `json:"reacted"`
+}
+
+// IsVote tells whether the reaction is a like or a dislike, which pages show on buttons of their own.
+func (r Reaction) IsVote() bool {
+	return r.Emoji == ReactionLike || r.Emoji == ReactionDislike
+}
+
+// Who names the users who reacted, for showing when hovering the reaction.
+func (r Reaction) Who() string {
+	names := strings.Join(r.Users, ", ")
+	if others := r.Count - len(r.Users); others > 0 {
+		return fmt.Sprintf("%s and %d more", names, others)
+	}
+	return names
+}
+
+// ReactionUser is a user who gave a reaction, ReactedAt is unknown for likes and dislikes given by older versions.
+type ReactionUser struct {
+	Username  string    `json:"username"`
+	ReactedAt Timestamp `json:"reactedAt"`
+}
+
+// ReactionUserPage is one page of the users who gave a reaction to a post or a comment, the latest first.
+type ReactionUserPage struct {
+	Emoji string         `json:"emoji"`
+	Users []ReactionUser `json:"users"`
+	Page  int            `json:"page"`
+	// More is set when another page of users follows.
+	More bool `json:"more"`
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// ReportStatus tells whether a report is waiting for a moderator and, if not, what was done about it.
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportDismissed ReportStatus = "dismissed"
+	ReportHidden    ReportStatus = "hidden"
+	ReportDeleted   ReportStatus = "deleted"
+)
+
+// Report flags a post or, when CommentID is set, a comment of the post for moderators.
+type Report struct {
+	ID          int
+	ReporterID  int
+	Reporter    string
+	PostID      int
+	CommentID   int
+	Reason      string
+	Status      ReportStatus
+	CreatedAt   time.Time
+	ModeratorID int
+	Moderator   string
+	ResolvedAt  time.Time
+	// Excerpt is the reported title or comment text, empty once the content is deleted.
+	Excerpt string
+}
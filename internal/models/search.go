@@ -0,0 +1,47 @@
+package models
+
+// SearchRequest asks for one page of search results.
+type SearchRequest struct {
+	// Query is the search text, words in double quotes are matched as a phrase.
+	Query string
+	// Category and Author narrow the results down when they are not empty.
+	Category string
+	Author   string
+	// Page counts from 1, zero means the first page.
+	Page int
+}
+
+// SearchKind tells whether a search result is a post or a comment.
+type SearchKind string
+
+const (
+	SearchPost    SearchKind = "post"
+	SearchComment SearchKind = "comment"
+)
+
+// SearchResult is a post or a comment that matched a search.
+type SearchResult struct {
+	Kind   SearchKind `json:"kind"`
+	PostID int        `json:"postId"`
+	// CommentID is zero when the result is a post.
+	CommentID int    `json:"commentId,omitempty"`
+	Title     string `json:"title"`
+	Author    string `json:"author"`
+	// Snippet is the matching part of the text, split where the highlighted search terms start and end.
+	Snippet   []SnippetPart `json:"snippet"`
+	CreatedAt Timestamp     `json:"createdAt"`
+}
+
+// SnippetPart is a piece of a search snippet, Match is set on the pieces that matched the search.
+type SnippetPart struct {
+	Text  string `json:"text"`
+	Match bool   `json:"match,omitempty"`
+}
+
+// SearchPage is one page of search results.
+type SearchPage struct {
+	Results []SearchResult `json:"results"`
+	Page    int            `json:"page"`
+	// More is set when another page of results follows.
+	More bool `json:"more"`
+}
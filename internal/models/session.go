@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is a single signed-in device of a user.
+type Session struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"userId"`
+	Token      string    `json:"-"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// Tag is a free-form label that users put on their posts, with the number of visible posts that carry it.
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
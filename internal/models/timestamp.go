@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timestamp is a point in time stored in a column that rows written by older versions leave empty.
+// The zero Timestamp stands for an unknown time.
+type Timestamp struct {
+	time.Time
+}
+
+// timestampLayouts are the layouts that SQLite returns times in when it does not parse them itself.
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// Scan implements sql.Scanner, a NULL becomes the zero Timestamp.
+func (t *Timestamp) Scan(value any) error {
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+	case time.Time:
+		t.Time = v
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	default:
+		return fmt.Errorf("models: cannot scan %T into a timestamp", value)
+	}
+	return nil
+}
+
+func (t *Timestamp) parse(value string) error {
+	for _, layout := range timestampLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("models: cannot parse timestamp %q", value)
+}
+
+// Value implements driver.Valuer, the zero Timestamp is stored as NULL.
+func (t Timestamp) Value() (driver.Value, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return t.Time, nil
+}
+
+// MarshalJSON writes the zero Timestamp as null.
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.Time)
+}
+
+// Ago describes how long ago the timestamp was, like "3 hours ago".
+// It is empty for the zero Timestamp.
+func (t Timestamp) Ago() string {
+	if t.IsZero() {
+		return ""
+	}
+
+	elapsed := time.Since(t.Time)
+	switch {
+	case elapsed < time.Minute:
+		return "just now"
+	case elapsed < time.Hour:
+		return plural(int(elapsed/time.Minute), "minute") + " ago"
+	case elapsed < 24*time.Hour:
+		return plural(int(elapsed/time.Hour), "hour") + " ago"
+	case elapsed < 30*24*time.Hour:
+		return plural(int(elapsed/(24*time.Hour)), "day") + " ago"
+	case elapsed < 365*24*time.Hour:
+		return plural(int(elapsed/(30*24*time.Hour)), "month") + " ago"
+	default:
+		return plural(int(elapsed/(365*24*time.Hour)), "year") + " ago"
+	}
+}
+
+// plural formats a count with its unit, like "1 hour" or "3 hours".
+func plural(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
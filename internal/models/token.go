@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AccessToken is a personal access token that lets scripts act on behalf of a user.
+// Token holds the plain text value and is only set right after the token is created.
+type AccessToken struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"userId"`
+	Name       string    `json:"name"`
+	Token      string    `json:"token,omitempty"`
+	TokenHash  string    `json:"-"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+}
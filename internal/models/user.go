@@ -2,11 +2,45 @@ package models
 
 import "time"
 
+// Role is the access level of a user.
+type Role string
+
+const (
+	RoleUser          Role = "user"
+	RoleModerator     Role = "moderator"
+	RoleAdministrator Role = "administrator"
+)
+
+// Roles lists every role from the least to the most privileged.
+var Roles = []Role{RoleUser, RoleModerator, RoleAdministrator}
+
 type User struct {
-	ID        int
-	Username  string
-	Email     string
-	Password  string
-	Token     string
-	ExpiresAt time.Time
+	ID        int       `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Password  string    `json:"-"`
+	Role      Role      `json:"role"`
+	CreatedAt Timestamp `json:"createdAt"`
+	UpdatedAt Timestamp `json:"updatedAt"`
+	Token     string    `json:"-"`
+	ExpiresAt time.Time `json:"-"`
+}
+
+// AtLeast reports whether r grants at least the access of min.
+func (r Role) AtLeast(min Role) bool {
+	return r.rank() >= min.rank()
+}
+
+// IsValid reports whether r is one of the known roles.
+func (r Role) IsValid() bool {
+	return r.rank() >= 0
+}
+
+func (r Role) rank() int {
+	for i, role := range Roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
 }
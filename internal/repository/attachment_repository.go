@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"forum/internal/filestore"
+	"forum/internal/models"
+	"io"
+)
+
+// AttachmentItem is an interface that defines the methods for storing the files attached to posts.
+type AttachmentItem interface {
+	CreateAttachment(attachment *models.Attachment, file, thumbnail []byte, fileExt, thumbnailExt string) error
+	GetAttachmentsByPostID(postID int) ([]models.Attachment, error)
+	GetAttachmentByID(id int) (models.Attachment, error)
+	OpenAttachmentFile(key string) (io.ReadSeekCloser, error)
+}
+
+// AttachmentStorage is a struct that implements the AttachmentItem interface.
+// Rows describing the attachments are kept in the database, their files in files.
+type AttachmentStorage struct {
+	db    *sql.DB
+	files filestore.Store
+}
+
+// NewAttachmentSqlite returns a new instance of AttachmentStorage.
+func NewAttachmentSqlite(db *sql.DB, files filestore.Store) *AttachmentStorage {
+	return &AttachmentStorage{db: db, files: files}
+}
+
+// CreateAttachment stores the file of an attachment, and its thumbnail unless it is empty, under new random keys
+// ending in the given extensions, then adds the attachment to the database.
+// The files are deleted again when the attachment cannot be added.
+func (a *AttachmentStorage) CreateAttachment(attachment *models.Attachment, file, thumbnail []byte, fileExt, thumbnailExt string) error {
+	base, err := newFileKey()
+	if err != nil {
+		return fmt.Errorf("storage: create attachment: %w", err)
+	}
+
+	attachment.FileKey = base + fileExt
+	if err = a.files.Save(attachment.FileKey, bytes.NewReader(file)); err != nil {
+		return fmt.Errorf("storage: create attachment: %w", err)
+	}
+	attachment.ThumbnailKey = ""
+	if len(thumbnail) > 0 {
+		attachment.ThumbnailKey = base + "-thumbnail" + thumbnailExt
+		if err = a.files.Save(attachment.ThumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+			deleteFiles(a.files, attachment.FileKey)
+			return fmt.Errorf("storage: create attachment: %w", err)
+		}
+	}
+
+	query := `INSERT INTO attachment (postid, name, contentType, size, width, height, fileKey, thumbnailKey, createdAt)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9);`
+	res, err := a.db.Exec(query, attachment.PostID, attachment.Name, attachment.ContentType, attachment.Size,
+		attachment.Width, attachment.Height, attachment.FileKey, attachment.ThumbnailKey, attachment.CreatedAt)
+	if err != nil {
+		deleteFiles(a.files, attachment.FileKey, attachment.ThumbnailKey)
+		return fmt.Errorf("storage: create attachment: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create attachment: %w", err)
+	}
+	attachment.ID = int(id)
+	return nil
+}
+
+// GetAttachmentsByPostID returns the attachments of a post in the order they were uploaded.
+func (a *AttachmentStorage) GetAttachmentsByPostID(postID int) ([]models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachment WHERE postid = $1 ORDER BY id;`
+	rows, err := a.db.Query(query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get attachments by post id: %w", err)
+	}
+	defer rows.Close()
+
+	var attachments []models.Attachment
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage: get attachments by post id: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+// GetAttachmentByID returns an attachment by its ID.
+func (a *AttachmentStorage) GetAttachmentByID(id int) (models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachment WHERE id = $1;`
+	attachment, err := scanAttachment(a.db.QueryRow(query, id))
+	if err != nil {
+		return models.Attachment{}, fmt.Errorf("storage: get attachment by id: %w", err)
+	}
+	return attachment, nil
+}
+
+// OpenAttachmentFile opens the file or thumbnail of an attachment by its key.
+func (a *AttachmentStorage) OpenAttachmentFile(key string) (io.ReadSeekCloser, error) {
+	file, err := a.files.Open(key)
+	if err != nil {
+		return nil, fmt.Errorf("storage: open attachment file: %w", err)
+	}
+	return file, nil
+}
+
+const attachmentColumns = `id, postid, name, contentType, size, width, height, fileKey, thumbnailKey, createdAt`
+
+// scanAttachment reads an attachment selected with attachmentColumns.
+func scanAttachment(row interface{ Scan(dest ...any) error }) (models.Attachment, error) {
+	var attachment models.Attachment
+	err := row.Scan(&attachment.ID, &attachment.PostID, &attachment.Name, &attachment.ContentType, &attachment.Size,
+		&attachment.Width, &attachment.Height, &attachment.FileKey, &attachment.ThumbnailKey, &attachment.CreatedAt)
+	return attachment, err
+}
+
+// newFileKey returns a random key for a new file, without extension.
+func newFileKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// deleteFiles deletes the files stored under keys, empty keys are skipped.
+// It goes on after a failure and returns the first error.
+func deleteFiles(files filestore.Store, keys ...string) error {
+	var first error
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := files.Delete(key); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
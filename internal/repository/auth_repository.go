@@ -4,17 +4,26 @@ import (
 	"database/sql"
 	"fmt"
 	"forum/internal/models"
+	"forum/internal/text"
 	"time"
 )
 
 // Authorization interface defines methods for user authentication and session management.
 type Authorization interface {
-	CreateUser(user *models.User) error
+	CreateUser(user *models.User, firstRole models.Role) error
 	GetUserByEmail(email string) (models.User, error)
 	GetUserByUsername(username string) (models.User, error)
-	AddSessionToken(email, token string, expiresAt time.Time) error
+	GetUserBySkeleton(skeleton string) (models.User, error)
+	GetAllUsers() ([]models.User, error)
+	UpdateUserRole(userID int, role models.Role, updatedAt time.Time) error
+	AddSessionToken(session *models.Session) error
 	GetSessionToken(token string) (models.User, error)
+	GetSessionsByUserID(userID int) ([]models.Session, error)
+	UpdateSessionLastSeen(token string, lastSeenAt time.Time) error
 	DeleteSessionToken(token string) error
+	DeleteUserSession(userID, sessionID int) (bool, error)
+	DeleteUserSessions(userID int) error
+	DeleteExpiredSessions(now time.Time) error
 }
 
 // AuthStorage is a struct that implements the Authorization interface.
@@ -27,23 +36,47 @@ func NewAuthSqlite(db *sql.DB) *AuthStorage {
 	return &AuthStorage{db: db}
 }
 
-// CreateUser creates a new user in the database.
-func (r *AuthStorage) CreateUser(user *models.User) error {
-	query := fmt.Sprintf("INSERT INTO user (username, email, password) values ($1, $2, $3)")
-	_, err := r.db.Exec(query, user.Username, user.Email, user.Password)
+// CreateUser creates a new user in the database, together with the skeleton of the username.
+// The first user of a forum gets firstRole instead of its own role. The users are counted in the same
+// transaction as the insert, so two sign-ups at the same time cannot both be the first.
+func (r *AuthStorage) CreateUser(user *models.User, firstRole models.Role) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage: create user: %w", err)
+	}
+	defer tx.Rollback()
+
+	var count int
+	if err = tx.QueryRow(`SELECT COUNT(*) FROM user;`).Scan(&count); err != nil {
+		return fmt.Errorf("storage: create user: %w", err)
+	}
+	if count == 0 {
+		user.Role = firstRole
+	}
+
+	query := `INSERT INTO user (username, email, password, role, createdAt, updatedAt, skeleton) values ($1, $2, $3, $4, $5, $6, $7)`
+	res, err := tx.Exec(query, user.Username, user.Email, user.Password, user.Role, user.CreatedAt, user.UpdatedAt, text.Skeleton(user.Username))
 	if err != nil {
 		return err
 	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create user: %w", err)
+	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: create user: %w", err)
+	}
+	user.ID = int(id)
 	return nil
 }
 
 // GetUserByEmail retrieves a user from the database by email.
 func (s *AuthStorage) GetUserByEmail(email string) (models.User, error) {
-	query := `SELECT id, email, username, password FROM user WHERE email=$1;`
+	query := `SELECT id, email, username, password, role, createdAt, updatedAt FROM user WHERE email=$1;`
 	row := s.db.QueryRow(query, email)
 	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return models.User{}, fmt.Errorf("storage: get user by email: %w", err)
 	}
@@ -52,20 +85,61 @@ func (s *AuthStorage) GetUserByEmail(email string) (models.User, error) {
 
 // GetUserByUsername retrieves a user from the database by username.
 func (s *AuthStorage) GetUserByUsername(username string) (models.User, error) {
-	query := `SELECT id, email, username, password FROM user WHERE username=$1;`
+	query := `SELECT id, email, username, password, role, createdAt, updatedAt FROM user WHERE username=$1;`
 	row := s.db.QueryRow(query, username)
 	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password)
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
 		return models.User{}, fmt.Errorf("storage: get user by username: %w", err)
 	}
 	return user, nil
 }
 
-// AddSessionToken adds a session token to a user in the database.
-func (s *AuthStorage) AddSessionToken(email, token string, expiresAt time.Time) error {
-	query := `UPDATE user SET token = $1, expiresAt = $2 WHERE email = $3;`
-	_, err := s.db.Exec(query, token, expiresAt, email)
+// GetUserBySkeleton retrieves a user whose username has the given skeleton, see text.Skeleton.
+func (s *AuthStorage) GetUserBySkeleton(skeleton string) (models.User, error) {
+	query := `SELECT id, email, username, password, role, createdAt, updatedAt FROM user WHERE skeleton=$1 LIMIT 1;`
+	row := s.db.QueryRow(query, skeleton)
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		return models.User{}, fmt.Errorf("storage: get user by skeleton: %w", err)
+	}
+	return user, nil
+}
+
+// GetAllUsers returns every user ordered by ID.
+func (s *AuthStorage) GetAllUsers() ([]models.User, error) {
+	query := `SELECT id, email, username, role, createdAt, updatedAt FROM user ORDER BY id;`
+	rows, err := s.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get all users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.Username, &user.Role, &user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("storage: get all users: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUserRole changes the role of a user.
+func (s *AuthStorage) UpdateUserRole(userID int, role models.Role, updatedAt time.Time) error {
+	query := `UPDATE user SET role = $1, updatedAt = $2 WHERE id = $3;`
+	if _, err := s.db.Exec(query, role, updatedAt, userID); err != nil {
+		return fmt.Errorf("storage: update user role: %w", err)
+	}
+	return nil
+}
+
+// AddSessionToken stores a new session for a user in the database.
+func (s *AuthStorage) AddSessionToken(session *models.Session) error {
+	query := `INSERT INTO session (userid, token, userAgent, ip, createdAt, lastSeenAt, expiresAt) VALUES ($1, $2, $3, $4, $5, $6, $7);`
+	_, err := s.db.Exec(query, session.UserID, session.Token, session.UserAgent, session.IP, session.CreatedAt, session.LastSeenAt, session.ExpiresAt)
 	if err != nil {
 		return fmt.Errorf("storage: save session token: %w", err)
 	}
@@ -74,23 +148,92 @@ func (s *AuthStorage) AddSessionToken(email, token string, expiresAt time.Time)
 
 // GetSessionToken retrieves a user from the database by session token.
 func (s *AuthStorage) GetSessionToken(token string) (models.User, error) {
-	query := `SELECT id, email, username, password, token, expiresAt FROM user WHERE token=$1;`
+	query := `SELECT user.id, user.email, user.username, user.password, user.role, user.createdAt, user.updatedAt,
+	session.token, session.expiresAt
+	FROM session INNER JOIN user ON user.id = session.userid WHERE session.token=$1;`
 
 	row := s.db.QueryRow(query, token)
 	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Token, &user.ExpiresAt)
+	err := row.Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&user.Token, &user.ExpiresAt)
 	if err != nil {
 		return models.User{}, fmt.Errorf("storage: get user by session token: %w", err)
 	}
 	return user, nil
 }
 
-// DeleteSessionToken removes a session token from a user in the database.
+// GetSessionsByUserID returns the active sessions of a user, most recently used first.
+func (s *AuthStorage) GetSessionsByUserID(userID int) ([]models.Session, error) {
+	query := `SELECT id, userid, token, userAgent, ip, createdAt, lastSeenAt, expiresAt FROM session
+	WHERE userid = $1 AND expiresAt > $2 ORDER BY lastSeenAt DESC;`
+	rows, err := s.db.Query(query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("storage: get sessions by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Token, &session.UserAgent, &session.IP,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("storage: get sessions by user id: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// UpdateSessionLastSeen records the last time a session was used.
+func (s *AuthStorage) UpdateSessionLastSeen(token string, lastSeenAt time.Time) error {
+	query := `UPDATE session SET lastSeenAt = $1 WHERE token = $2;`
+	_, err := s.db.Exec(query, lastSeenAt, token)
+	if err != nil {
+		return fmt.Errorf("storage: update session last seen: %w", err)
+	}
+	return nil
+}
+
+// DeleteSessionToken removes a single session from the database.
 func (s *AuthStorage) DeleteSessionToken(token string) error {
-	query := `UPDATE user SET token = NULL, expiresAt = NULL WHERE token = $1;`
+	query := `DELETE FROM session WHERE token = $1;`
 	_, err := s.db.Exec(query, token)
 	if err != nil {
 		return fmt.Errorf("storage: delete session token: %w", err)
 	}
 	return nil
 }
+
+// DeleteUserSession removes one session of a user and reports whether it existed.
+func (s *AuthStorage) DeleteUserSession(userID, sessionID int) (bool, error) {
+	query := `DELETE FROM session WHERE id = $1 AND userid = $2;`
+	res, err := s.db.Exec(query, sessionID, userID)
+	if err != nil {
+		return false, fmt.Errorf("storage: delete user session: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage: delete user session: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteUserSessions removes every session of a user from the database.
+func (s *AuthStorage) DeleteUserSessions(userID int) error {
+	query := `DELETE FROM session WHERE userid = $1;`
+	_, err := s.db.Exec(query, userID)
+	if err != nil {
+		return fmt.Errorf("storage: delete user sessions: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions that expired before the given time.
+func (s *AuthStorage) DeleteExpiredSessions(now time.Time) error {
+	query := `DELETE FROM session WHERE expiresAt < $1;`
+	_, err := s.db.Exec(query, now)
+	if err != nil {
+		return fmt.Errorf("storage: delete expired sessions: %w", err)
+	}
+	return nil
+}
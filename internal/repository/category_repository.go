@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"time"
+)
+
+// CategoryItem is an interface that defines the methods for storing categories.
+type CategoryItem interface {
+	CreateCategory(category *models.Category) error
+	ListCategories(archived bool) ([]models.Category, error)
+	GetCategoryByID(id int) (models.Category, error)
+	GetCategoryBySlug(slug string) (models.Category, error)
+	UpdateCategory(category models.Category) error
+	SetCategoryArchived(id int, archived bool) error
+	SetCategorySubscription(userID, categoryID int, subscribed bool, at time.Time) error
+	GetSubscribedCategories(userID int) ([]models.Category, error)
+}
+
+// CategoryStorage is a struct that implements the CategoryItem interface.
+type CategoryStorage struct {
+	db *sql.DB
+}
+
+// NewCategorySqlite returns a new instance of CategoryStorage.
+func NewCategorySqlite(db *sql.DB) *CategoryStorage {
+	return &CategoryStorage{db: db}
+}
+
+// categoryColumns selects a category in the order that scanCategory reads it.
+const categoryColumns = `SELECT id, slug, name, description, position, archived FROM category`
+
+// scanCategory reads a category selected with categoryColumns.
+func scanCategory(row interface{ Scan(dest ...any) error }) (models.Category, error) {
+	var c models.Category
+	err := row.Scan(&c.ID, &c.Slug, &c.Name, &c.Description, &c.Position, &c.Archived)
+	return c, err
+}
+
+// CreateCategory stores a new category.
+func (s *CategoryStorage) CreateCategory(category *models.Category) error {
+	query := `INSERT INTO category (slug, name, description, position, archived) VALUES ($1, $2, $3, $4, $5);`
+	res, err := s.db.Exec(query, category.Slug, category.Name, category.Description, category.Position, category.Archived)
+	if err != nil {
+		return fmt.Errorf("storage: create category: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create category: %w", err)
+	}
+	category.ID = int(id)
+	return nil
+}
+
+// ListCategories returns the categories in navigation order, the archived ones only when archived is set.
+func (s *CategoryStorage) ListCategories(archived bool) ([]models.Category, error) {
+	query := categoryColumns
+	if !archived {
+		query += ` WHERE archived = 0`
+	}
+	query += ` ORDER BY position, id;`
+
+	categories, err := s.queryCategories(query)
+	if err != nil {
+		return nil, fmt.Errorf("storage: list categories: %w", err)
+	}
+	return categories, nil
+}
+
+// queryCategories returns the categories selected by a query that starts with categoryColumns.
+func (s *CategoryStorage) queryCategories(query string, args ...any) ([]models.Category, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		c, err := scanCategory(rows)
+		if err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+// GetCategoryByID returns the category with a specific ID.
+func (s *CategoryStorage) GetCategoryByID(id int) (models.Category, error) {
+	c, err := scanCategory(s.db.QueryRow(categoryColumns+` WHERE id = $1;`, id))
+	if err != nil {
+		return models.Category{}, fmt.Errorf("storage: get category by id: %w", err)
+	}
+	return c, nil
+}
+
+// GetCategoryBySlug returns the category with a specific slug.
+func (s *CategoryStorage) GetCategoryBySlug(slug string) (models.Category, error) {
+	c, err := scanCategory(s.db.QueryRow(categoryColumns+` WHERE slug = $1;`, slug))
+	if err != nil {
+		return models.Category{}, fmt.Errorf("storage: get category by slug: %w", err)
+	}
+	return c, nil
+}
+
+// UpdateCategory changes the name, description and position of a category.
+func (s *CategoryStorage) UpdateCategory(category models.Category) error {
+	query := `UPDATE category SET name = $1, description = $2, position = $3 WHERE id = $4;`
+	if _, err := s.db.Exec(query, category.Name, category.Description, category.Position, category.ID); err != nil {
+		return fmt.Errorf("storage: update category: %w", err)
+	}
+	return nil
+}
+
+// SetCategoryArchived archives a category or brings it back.
+func (s *CategoryStorage) SetCategoryArchived(id int, archived bool) error {
+	query := `UPDATE category SET archived = $1 WHERE id = $2;`
+	if _, err := s.db.Exec(query, archived, id); err != nil {
+		return fmt.Errorf("storage: set category archived: %w", err)
+	}
+	return nil
+}
+
+// SetCategorySubscription subscribes a user to a category or unsubscribes them.
+func (s *CategoryStorage) SetCategorySubscription(userID, categoryID int, subscribed bool, at time.Time) error {
+	query := `DELETE FROM category_subscription WHERE userid = $1 AND categoryid = $2;`
+	args := []any{userID, categoryID}
+	if subscribed {
+		query = `INSERT OR IGNORE INTO category_subscription (userid, categoryid, createdAt) VALUES ($1, $2, $3);`
+		args = append(args, at)
+	}
+	if _, err := s.db.Exec(query, args...); err != nil {
+		return fmt.Errorf("storage: set category subscription: %w", err)
+	}
+	return nil
+}
+
+// GetSubscribedCategories returns the categories that a user subscribed to, in navigation order.
+func (s *CategoryStorage) GetSubscribedCategories(userID int) ([]models.Category, error) {
+	query := categoryColumns + ` WHERE id IN (SELECT categoryid FROM category_subscription WHERE userid = $1) ORDER BY position, id;`
+	categories, err := s.queryCategories(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get subscribed categories: %w", err)
+	}
+	return categories, nil
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"forum/internal/models"
+	"time"
 )
 
 // Comment is an interface that defines methods for interacting with comments in the database.
@@ -11,14 +12,16 @@ type Comment interface {
 	CreateComment(comment *models.Comment) error
 	GetComments(postID int) ([]*models.Comment, error)
 	GetCommentByID(commentID int) (models.Comment, error)
-	CommentHasLike(commentID int, username string) error
-	CommentHasDislike(commentID int, username string) error
-	RemoveLikeComment(commentID int, username string) error
-	RemoveDislikeComment(commentID int, username string) error
-	LikeComment(commentID int, username string) error
-	DislikeComment(commentID int, username string) error
+	SetCommentHidden(commentID int, hidden bool) error
+	DeleteComment(commentID int) error
+	ModerateComment(commentID int, action models.ReportStatus, moderatorID int, resolvedAt time.Time) error
+	UpdateComment(commentID int, text string, editorID int, editedAt time.Time) error
+	GetCommentRevisions(commentID int) ([]models.CommentRevision, error)
 }
 
+// commentEdited selects whether a comment has been edited.
+const commentEdited = `EXISTS (SELECT 1 FROM comment_revision WHERE comment_revision.commentid = comment.id)`
+
 // CommentStorage is a struct that implements the Comment interface.
 type CommentStorage struct {
 	db *sql.DB
@@ -29,138 +32,283 @@ func NewCommentSqlite(db *sql.DB) *CommentStorage {
 	return &CommentStorage{db: db}
 }
 
-// CreateComment creates a new comment in the database.
+// recountComments sets the commentCount of the post $1 to the number of its visible comments.
+const recountComments = `UPDATE post SET commentCount = (SELECT COUNT(*) FROM comment
+	WHERE comment.postid = post.id AND comment.hidden = 0 AND comment.deleted = 0) WHERE id = $1;`
+
+// CreateComment creates a new comment in the database and counts it on its post.
 func (c *CommentStorage) CreateComment(comment *models.Comment) error {
-	query := fmt.Sprintf(`INSERT INTO comment (author, text, postid) values ($1, $2, $3)`)
-	res, err := c.db.Exec(query, comment.Author, comment.Text, comment.PostID)
+	tx, err := c.db.Begin()
 	if err != nil {
-		return err
+		return fmt.Errorf("storage: create comment: %w", err)
 	}
-	_, err = res.LastInsertId()
+	defer tx.Rollback()
+
+	query := `INSERT INTO comment (author, text, postid, parentid, createdAt, updatedAt) values ($1, $2, $3, $4, $5, $6)`
+	res, err := tx.Exec(query, comment.Author, comment.Text, comment.PostID, comment.ParentID, comment.CreatedAt, comment.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("repository: create commentary: Insert query - %w", err)
+		return fmt.Errorf("storage: create comment: %w", err)
 	}
-	_, err = res.RowsAffected()
+	id, err := res.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("repository: create commentary: Insert query - %w", err)
+		return fmt.Errorf("storage: create comment: %w", err)
 	}
+	comment.ID = int(id)
 
+	if _, err = tx.Exec(recountComments, comment.PostID); err != nil {
+		return fmt.Errorf("storage: create comment: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: create comment: %w", err)
+	}
 	return nil
 }
 
-// GetComments returns all comments for a given post ID.
+// GetComments returns the comments of a given post in thread order: every reply follows its parent
+// and carries its depth below the top-level comment. Hidden and deleted comments are only kept,
+// with their author and text cleared, while they still have replies to hold together.
 func (c *CommentStorage) GetComments(postID int) ([]*models.Comment, error) {
 	var comments []*models.Comment
-	query := fmt.Sprintf(`SELECT * FROM comment WHERE postid = $1;`)
+	query := `SELECT id, author, postid, parentid, text, like, dislike, hidden, deleted, createdAt, updatedAt, ` + commentEdited + ` FROM comment WHERE postid = $1 ORDER BY id;`
 	rows, err := c.db.Query(query, postID)
 	if err != nil {
 		return nil, fmt.Errorf("repository: get commentaries of the post: query - %w", err)
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		c := &models.Comment{}
-		if err = rows.Scan(&c.ID, &c.Author, &c.PostID, &c.Text, &c.Likes, &c.DisLikes); err != nil {
+		if err = rows.Scan(&c.ID, &c.Author, &c.PostID, &c.ParentID, &c.Text, &c.Likes, &c.DisLikes, &c.Hidden, &c.Deleted, &c.CreatedAt, &c.UpdatedAt, &c.Edited); err != nil {
 			return nil, fmt.Errorf("repository: get commentaries of the post: query - %w", err)
 		}
 		comments = append(comments, c)
 	}
-	rows.Close()
-	return comments, nil
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repository: get commentaries of the post: query - %w", err)
+	}
+
+	return threadComments(comments), nil
+}
+
+// threadComments orders comments depth first and sets their depth.
+// Comments whose parent is missing are treated as top-level comments.
+func threadComments(comments []*models.Comment) []*models.Comment {
+	byID := make(map[int]bool, len(comments))
+	for _, comment := range comments {
+		byID[comment.ID] = true
+	}
+
+	replies := make(map[int][]*models.Comment)
+	for _, comment := range comments {
+		parentID := comment.ParentID
+		if !byID[parentID] {
+			parentID = 0
+		}
+		replies[parentID] = append(replies[parentID], comment)
+	}
+
+	var thread func(comment *models.Comment, depth int) []*models.Comment
+	thread = func(comment *models.Comment, depth int) []*models.Comment {
+		var below []*models.Comment
+		for _, reply := range replies[comment.ID] {
+			below = append(below, thread(reply, depth+1)...)
+		}
+		if comment.Hidden || comment.Deleted {
+			if len(below) == 0 {
+				return nil
+			}
+			comment.Author, comment.Text = "", ""
+		}
+		comment.Depth = depth
+		return append([]*models.Comment{comment}, below...)
+	}
+
+	ordered := []*models.Comment{}
+	for _, comment := range replies[0] {
+		ordered = append(ordered, thread(comment, 0)...)
+	}
+	return ordered
 }
 
 // GetCommentByID returns a comment with a given ID.
 func (c *CommentStorage) GetCommentByID(commentID int) (models.Comment, error) {
 	var comment models.Comment
 
-	query := `SELECT id, postid, author, text, like, dislike FROM comment WHERE id=$1;`
+	query := `SELECT id, postid, parentid, author, text, like, dislike, hidden, deleted, createdAt, updatedAt, ` + commentEdited + ` FROM comment WHERE id=$1;`
 	row := c.db.QueryRow(query, commentID)
 
-	err := row.Scan(&comment.ID, &comment.PostID, &comment.Author, &comment.Text, &comment.Likes, &comment.DisLikes)
+	err := row.Scan(&comment.ID, &comment.PostID, &comment.ParentID, &comment.Author, &comment.Text, &comment.Likes, &comment.DisLikes, &comment.Hidden, &comment.Deleted, &comment.CreatedAt, &comment.UpdatedAt, &comment.Edited)
 	if err != nil {
-		return models.Comment{}, fmt.Errorf("storage: get user by login: %w", err)
+		return models.Comment{}, fmt.Errorf("storage: get comment by id: %w", err)
 	}
 
 	return comment, nil
 }
 
-// RemoveLikeComment removes a like from a comment.
-func (s *CommentStorage) RemoveLikeComment(commentID int, username string) error {
-	query := `DELETE FROM like WHERE commentId = $1 AND username = $2;`
-	_, err := s.db.Exec(query, commentID, username)
+// SetCommentHidden hides a comment from its post or makes it visible again.
+// The comment count of its post is updated in the same transaction.
+func (s *CommentStorage) SetCommentHidden(commentID int, hidden bool) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("storage: remove like from comment: %w", err)
+		return fmt.Errorf("storage: set comment hidden: %w", err)
 	}
-	query = `UPDATE comment SET like = like - 1 WHERE id = $1;`
-	_, err = s.db.Exec(query, commentID)
-	if err != nil {
-		return fmt.Errorf("storage: remove like from comment: %w", err)
+	defer tx.Rollback()
+
+	if err = setCommentHidden(tx, commentID, hidden); err != nil {
+		return fmt.Errorf("storage: set comment hidden: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: set comment hidden: %w", err)
 	}
 	return nil
 }
 
-// RemoveDislikeComment removes a dislike from a comment.
-func (s *CommentStorage) RemoveDislikeComment(commentID int, username string) error {
-	query := `DELETE FROM dislike WHERE commentId = $1 AND username = $2;`
-	_, err := s.db.Exec(query, commentID, username)
-	if err != nil {
-		return fmt.Errorf("storage: remove like from comment: %w", err)
+// setCommentHidden hides or shows a comment in tx and recounts the comments of its post.
+func setCommentHidden(tx *sql.Tx, commentID int, hidden bool) error {
+	var postID int
+	if err := tx.QueryRow(`SELECT postid FROM comment WHERE id = $1;`, commentID).Scan(&postID); err != nil {
+		return err
 	}
-	query = `UPDATE comment SET dislike = dislike - 1 WHERE id = $1;`
-	_, err = s.db.Exec(query, commentID)
-	if err != nil {
-		return fmt.Errorf("storage: remove like from comment: %w", err)
+
+	query := `UPDATE comment SET hidden = $1 WHERE id = $2;`
+	if _, err := tx.Exec(query, hidden, commentID); err != nil {
+		return err
 	}
-	return nil
+	_, err := tx.Exec(recountComments, postID)
+	return err
 }
 
-// CommentHasLike checks if a comment has a like from a given user.
-func (s *CommentStorage) CommentHasLike(commentID int, username string) error {
-	var u, query string
-	query = `SELECT username FROM like WHERE commentId = $1 AND username = $2;`
-	err := s.db.QueryRow(query, commentID, username).Scan(&u)
+// DeleteComment deletes a comment together with its reactions, revisions and open reports, and updates the comment
+// count of its post, in one transaction.
+// A comment that has replies is kept as a "[deleted]" placeholder so the replies stay in their thread, and its
+// revisions are kept with it for moderators.
+func (s *CommentStorage) DeleteComment(commentID int) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("storage: comment has like: %w", err)
+		return fmt.Errorf("storage: delete comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err = deleteComment(tx, commentID); err != nil {
+		return fmt.Errorf("storage: delete comment: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: delete comment: %w", err)
 	}
 	return nil
 }
 
-// CommentHasDislike checks if a comment has a dislike from a given user.
-func (s *CommentStorage) CommentHasDislike(commentID int, username string) error {
-	var u, query string
-	query = `SELECT username FROM dislike WHERE commentId = $1 AND username = $2;`
-	err := s.db.QueryRow(query, commentID, username).Scan(&u)
-	if err != nil {
-		return fmt.Errorf("storage: comment has like: %w", err)
+// deleteComment deletes a comment or leaves its placeholder in tx and recounts the comments of its post.
+func deleteComment(tx *sql.Tx, commentID int) error {
+	var postID, replies int
+	query := `SELECT postid, (SELECT COUNT(*) FROM comment AS reply WHERE reply.parentid = comment.id) FROM comment WHERE id = $1;`
+	if err := tx.QueryRow(query, commentID).Scan(&postID, &replies); err != nil {
+		return err
 	}
-	return nil
+
+	queries := []string{
+		`DELETE FROM reaction WHERE commentId = $1;`,
+		`DELETE FROM report WHERE commentid = $1 AND status = '` + string(models.ReportOpen) + `';`,
+	}
+	if replies > 0 {
+		queries = append(queries, `UPDATE comment SET deleted = 1, text = '', like = 0, dislike = 0 WHERE id = $1;`)
+	} else {
+		queries = append(queries, `DELETE FROM comment_revision WHERE commentid = $1;`, `DELETE FROM comment WHERE id = $1;`)
+	}
+
+	for _, query := range queries {
+		if _, err := tx.Exec(query, commentID); err != nil {
+			return err
+		}
+	}
+	_, err := tx.Exec(recountComments, postID)
+	return err
 }
 
-// LikeComment adds a like to a comment.
-func (s *CommentStorage) LikeComment(commentID int, username string) error {
-	query := `INSERT INTO like(commentId, username) VALUES ($1, $2);`
-	_, err := s.db.Exec(query, commentID, username)
+// ModerateComment hides or deletes a comment for a moderator and closes the open reports on it with that action,
+// in one transaction, so that the reports are never closed while the comment stays up.
+func (s *CommentStorage) ModerateComment(commentID int, action models.ReportStatus, moderatorID int, resolvedAt time.Time) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("storage: like comment: %w", err)
+		return fmt.Errorf("storage: moderate comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	var postID int
+	if err = tx.QueryRow(`SELECT postid FROM comment WHERE id = $1;`, commentID).Scan(&postID); err != nil {
+		return fmt.Errorf("storage: moderate comment: %w", err)
+	}
+	if err = resolveReports(tx, postID, commentID, action, moderatorID, resolvedAt); err != nil {
+		return fmt.Errorf("storage: moderate comment: %w", err)
+	}
+
+	switch action {
+	case models.ReportHidden:
+		err = setCommentHidden(tx, commentID, true)
+	case models.ReportDeleted:
+		err = deleteComment(tx, commentID)
+	default:
+		err = fmt.Errorf("unknown action %q", action)
 	}
-	query = `UPDATE comment SET like = like + 1  WHERE id = $1;`
-	_, err = s.db.Exec(query, commentID)
 	if err != nil {
-		return fmt.Errorf("storage: like comment: %w", err)
+		return fmt.Errorf("storage: moderate comment: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: moderate comment: %w", err)
 	}
 	return nil
 }
 
-// DislikeComment adds a dislike to a comment.
-func (s *CommentStorage) DislikeComment(commentID int, username string) error {
-	query := `INSERT INTO dislike(commentId, username) VALUES ($1, $2);`
-	_, err := s.db.Exec(query, commentID, username)
+// UpdateComment replaces the text of a comment and keeps the previous text as a revision, in one transaction.
+func (s *CommentStorage) UpdateComment(commentID int, text string, editorID int, editedAt time.Time) error {
+	tx, err := s.db.Begin()
 	if err != nil {
-		return fmt.Errorf("storage: like comment: %w", err)
+		return fmt.Errorf("storage: update comment: %w", err)
 	}
-	query = `UPDATE comment SET dislike = dislike + 1  WHERE id = $1;`
-	_, err = s.db.Exec(query, commentID)
-	if err != nil {
-		return fmt.Errorf("storage: like comment: %w", err)
+	defer tx.Rollback()
+
+	query := `INSERT INTO comment_revision (commentid, text, editorid, editedAt) SELECT id, text, ?, ? FROM comment WHERE id = ?;`
+	if _, err = tx.Exec(query, editorID, editedAt, commentID); err != nil {
+		return fmt.Errorf("storage: update comment: %w", err)
+	}
+
+	query = `UPDATE comment SET text = $1, updatedAt = $2 WHERE id = $3;`
+	if _, err = tx.Exec(query, text, editedAt, commentID); err != nil {
+		return fmt.Errorf("storage: update comment: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: update comment: %w", err)
 	}
 	return nil
 }
+
+// GetCommentRevisions returns the earlier texts of a comment, the latest edit first.
+func (s *CommentStorage) GetCommentRevisions(commentID int) ([]models.CommentRevision, error) {
+	query := `SELECT comment_revision.id, comment_revision.commentid, comment_revision.text, comment_revision.editorid,
+		COALESCE(user.username, ''), comment_revision.editedAt
+		FROM comment_revision LEFT JOIN user ON user.id = comment_revision.editorid
+		WHERE comment_revision.commentid = $1 ORDER BY comment_revision.id DESC;`
+	rows, err := s.db.Query(query, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get comment revisions: %w", err)
+	}
+	defer rows.Close()
+
+	var revisions []models.CommentRevision
+	for rows.Next() {
+		var r models.CommentRevision
+		if err = rows.Scan(&r.ID, &r.CommentID, &r.Text, &r.EditorID, &r.Editor, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("storage: get comment revisions: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage: get comment revisions: %w", err)
+	}
+	return revisions, nil
+}
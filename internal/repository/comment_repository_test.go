@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"forum/internal/models"
+)
+
+// TestDeleteCommentRevisions checks that a deleted comment loses its revisions with it, and that one kept as a
+// placeholder for its replies keeps them.
+func TestDeleteCommentRevisions(t *testing.T) {
+	db := testDB(t)
+	comments := NewCommentSqlite(db)
+	post := createTestPost(t, NewPostSqlite(db, nil), "Post")
+
+	parent := createTestComment(t, db, post.Id, "alice")
+	now := models.Timestamp{Time: time.Now()}
+	reply := models.Comment{PostID: post.Id, ParentID: parent.ID, Author: "bob", Text: "reply", CreatedAt: now, UpdatedAt: now}
+	if err := comments.CreateComment(&reply); err != nil {
+		t.Fatal(err)
+	}
+	single := createTestComment(t, db, post.Id, "alice")
+
+	for _, comment := range []models.Comment{parent, single} {
+		if err := comments.UpdateComment(comment.ID, "edited", 1, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+		if err := comments.DeleteComment(comment.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		commentID int
+		revisions int
+	}{
+		{"placeholder", parent.ID, 1},
+		{"deleted", single.ID, 0},
+	}
+	for _, tt := range tests {
+		revisions, err := comments.GetCommentRevisions(tt.commentID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(revisions) != tt.revisions {
+			t.Errorf("%s: %d revisions, want %d", tt.name, len(revisions), tt.revisions)
+		}
+	}
+}
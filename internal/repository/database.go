@@ -2,10 +2,13 @@ package repository
 
 import (
 	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/text"
 )
 
 func NewDB() (*sql.DB, error) {
-	db, err := sql.Open("sqlite3", "database.db")
+	db, err := sql.Open("sqlite3", "database.db?_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
@@ -17,23 +20,330 @@ func NewDB() (*sql.DB, error) {
 }
 
 func CreateTables(db *sql.DB) error {
-	tables := []string{userTable, postTable, commentTable, likeTable, dislikeTable, postCategoryTable}
+	tables := []string{userTable, sessionTable, accessTokenTable, postTable, commentTable, reactionTable, categoryTable, postCategoryTable, categorySubscriptionTable, tagTable, postTagTable, attachmentTable, reportTable, commentRevisionTable, postRevisionTable}
 	for _, v := range tables {
 		_, err := db.Exec(v)
 		if err != nil {
 			return err
 		}
 	}
+	if err := seedCategories(db); err != nil {
+		return err
+	}
+	if err := addMissingColumns(db); err != nil {
+		return err
+	}
+	for _, v := range indexes {
+		if _, err := db.Exec(v); err != nil {
+			return fmt.Errorf("storage: create index: %w", err)
+		}
+	}
+	if err := migrateVotes(db); err != nil {
+		return err
+	}
+	return createSearchIndex(db)
+}
+
+// SearchAvailable reports whether the SQLite library supports the full-text search index.
+func SearchAvailable(db *sql.DB) (bool, error) {
+	var enabled bool
+	if err := db.QueryRow(`SELECT sqlite_compileoption_used('ENABLE_FTS5');`).Scan(&enabled); err != nil {
+		return false, fmt.Errorf("storage: search available: %w", err)
+	}
+	return enabled, nil
+}
+
+// createSearchIndex creates the full-text search tables and the triggers that keep them in sync with posts and comments.
+// Without FTS5 it drops the triggers instead, so that posts and comments can still be written, and the index is
+// rebuilt from scratch the next time the triggers are created.
+func createSearchIndex(db *sql.DB) error {
+	available, err := SearchAvailable(db)
+	if err != nil {
+		return err
+	}
+	if !available {
+		for _, trigger := range searchTriggerNames {
+			if _, err = db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s;", trigger)); err != nil {
+				return fmt.Errorf("storage: drop trigger %s: %w", trigger, err)
+			}
+		}
+		return nil
+	}
+
+	var synced bool
+	if err = db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'trigger' AND name = $1);`, searchTriggerNames[0]).Scan(&synced); err != nil {
+		return fmt.Errorf("storage: create search index: %w", err)
+	}
+
+	for _, v := range searchTables {
+		if _, err = db.Exec(v); err != nil {
+			return fmt.Errorf("storage: create search index: %w", err)
+		}
+	}
+	if synced {
+		return nil
+	}
+
+	for _, v := range []string{`INSERT INTO post_search (post_search) VALUES ('rebuild');`, `INSERT INTO comment_search (comment_search) VALUES ('rebuild');`} {
+		if _, err = db.Exec(v); err != nil {
+			return fmt.Errorf("storage: rebuild search index: %w", err)
+		}
+	}
+	return nil
+}
+
+// defaultCategories are the categories of a new forum, they were the only ones before categories could be managed.
+var defaultCategories = []string{"Golang", "Python", "JavaScript", "Docker", "SQL"}
+
+// seedCategories fills an empty category table with the default categories.
+func seedCategories(db *sql.DB) error {
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM category;`).Scan(&count); err != nil {
+		return fmt.Errorf("storage: seed categories: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	for i, name := range defaultCategories {
+		query := `INSERT INTO category (slug, name, description, position) VALUES ($1, $2, '', $3);`
+		if _, err := db.Exec(query, models.CategorySlug(name), name, i+1); err != nil {
+			return fmt.Errorf("storage: seed categories: %w", err)
+		}
+	}
 	return nil
 }
 
+// linkPostCategories fills post_category.categoryid from the category names that older versions stored.
+// Names that are not a category yet, which older versions accepted through the API, become archived categories.
+func linkPostCategories(db *sql.DB) error {
+	rows, err := db.Query(`SELECT DISTINCT category FROM post_category
+		WHERE category IS NOT NULL AND category NOT IN (SELECT name FROM category);`)
+	if err != nil {
+		return fmt.Errorf("storage: link post categories: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			rows.Close()
+			return fmt.Errorf("storage: link post categories: %w", err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("storage: link post categories: %w", err)
+	}
+
+	for _, name := range names {
+		slug, err := freeCategorySlug(db, name)
+		if err != nil {
+			return err
+		}
+		query := `INSERT INTO category (slug, name, description, position, archived)
+			SELECT $1, $2, '', COALESCE(MAX(position), 0) + 1, 1 FROM category;`
+		if _, err = db.Exec(query, slug, name); err != nil {
+			return fmt.Errorf("storage: link post categories: %w", err)
+		}
+	}
+
+	query := `UPDATE post_category SET categoryid = (SELECT id FROM category WHERE category.name = post_category.category) WHERE categoryid IS NULL;`
+	if _, err = db.Exec(query); err != nil {
+		return fmt.Errorf("storage: link post categories: %w", err)
+	}
+	return nil
+}
+
+// freeCategorySlug derives a slug from name that no category has yet, numbering it when needed.
+func freeCategorySlug(db *sql.DB, name string) (string, error) {
+	base := models.CategorySlug(name)
+	if base == "" {
+		base = "category"
+	}
+	for n := 1; ; n++ {
+		slug := base
+		if n > 1 {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		var taken bool
+		if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM category WHERE slug = $1);`, slug).Scan(&taken); err != nil {
+			return "", fmt.Errorf("storage: free category slug: %w", err)
+		}
+		if !taken {
+			return slug, nil
+		}
+	}
+}
+
+// fillUserSkeletons fills user.skeleton from the usernames of the users that older versions signed up.
+func fillUserSkeletons(db *sql.DB) error {
+	rows, err := db.Query(`SELECT id, COALESCE(username, '') FROM user;`)
+	if err != nil {
+		return fmt.Errorf("storage: fill user skeletons: %w", err)
+	}
+	skeletons := make(map[int]string)
+	for rows.Next() {
+		var (
+			id       int
+			username string
+		)
+		if err = rows.Scan(&id, &username); err != nil {
+			rows.Close()
+			return fmt.Errorf("storage: fill user skeletons: %w", err)
+		}
+		skeletons[id] = text.Skeleton(username)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("storage: fill user skeletons: %w", err)
+	}
+
+	for id, skeleton := range skeletons {
+		if _, err = db.Exec(`UPDATE user SET skeleton = $1 WHERE id = $2;`, skeleton, id); err != nil {
+			return fmt.Errorf("storage: fill user skeletons: %w", err)
+		}
+	}
+	return nil
+}
+
+// countComments fills post.commentCount with the number of visible comments of every post.
+func countComments(db *sql.DB) error {
+	query := `UPDATE post SET commentCount = (SELECT COUNT(*) FROM comment
+		WHERE comment.postid = post.id AND comment.hidden = 0 AND comment.deleted = 0);`
+	if _, err := db.Exec(query); err != nil {
+		return fmt.Errorf("storage: count comments: %w", err)
+	}
+	return nil
+}
+
+// indexes speed up the lookups that post listings make for every post on a page, and post_top, post_discussed and
+// post_controversial hold the posts in the order of the sort keys of postSortKeys. They are not limited to visible
+// posts: the planner would then scan them in place of the post table for the hot order, which no index can serve.
+// They are created after addMissingColumns because some of them cover added columns.
+// post_category_post and post_category_category covered the category names that post_category held before
+// it pointed at the category table. The unique indexes of reaction keep a user from giving the same reaction
+// to a post or a comment twice.
+var indexes = []string{
+	`DROP INDEX IF EXISTS post_category_post;`,
+	`DROP INDEX IF EXISTS post_category_category;`,
+	`CREATE INDEX IF NOT EXISTS post_category_by_post ON post_category (postId, categoryid);`,
+	`CREATE INDEX IF NOT EXISTS post_category_by_category ON post_category (categoryid, postId);`,
+	`CREATE INDEX IF NOT EXISTS post_tag_by_tag ON post_tag (tagid, postid);`,
+	`CREATE INDEX IF NOT EXISTS comment_post ON comment (postid, hidden, deleted);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS reaction_post ON reaction (postid, emoji, username) WHERE commentId IS NULL;`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS reaction_comment ON reaction (commentId, emoji, username) WHERE commentId IS NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS reaction_username ON reaction (username, emoji, postid);`,
+	`CREATE INDEX IF NOT EXISTS user_skeleton ON user (skeleton);`,
+	`CREATE INDEX IF NOT EXISTS attachment_by_post ON attachment (postid);`,
+	`CREATE INDEX IF NOT EXISTS post_top ON post (like, id);`,
+	`CREATE INDEX IF NOT EXISTS post_discussed ON post (commentCount, id);`,
+	`CREATE INDEX IF NOT EXISTS post_controversial ON post (MIN(like, dislike), like + dislike, id);`,
+}
+
+// addedColumns lists columns that were added to tables after the tables were first created.
+// Databases created by older versions get them through addMissingColumns, which then runs fill, if any,
+// to derive the values of the new column from the old data.
+var addedColumns = []struct {
+	table      string
+	column     string
+	definition string
+	fill       func(db *sql.DB) error
+}{
+	{"user", "role", "TEXT NOT NULL DEFAULT 'user'", nil},
+	{"post", "hidden", "INTEGER NOT NULL DEFAULT 0", nil},
+	{"comment", "hidden", "INTEGER NOT NULL DEFAULT 0", nil},
+	{"comment", "parentid", "INTEGER NOT NULL DEFAULT 0", nil},
+	{"comment", "deleted", "INTEGER NOT NULL DEFAULT 0", nil},
+	{"user", "createdAt", "DATETIME", nil},
+	{"user", "updatedAt", "DATETIME", nil},
+	{"post", "createdAt", "DATETIME", nil},
+	{"post", "updatedAt", "DATETIME", nil},
+	{"comment", "createdAt", "DATETIME", nil},
+	{"comment", "updatedAt", "DATETIME", nil},
+	{"post_category", "categoryid", "INTEGER", linkPostCategories},
+	{"user", "skeleton", "TEXT NOT NULL DEFAULT ''", fillUserSkeletons},
+	{"post", "commentCount", "INTEGER NOT NULL DEFAULT 0", countComments},
+}
+
+// addMissingColumns adds the columns of addedColumns that an existing database lacks.
+func addMissingColumns(db *sql.DB) error {
+	for _, c := range addedColumns {
+		exists, err := columnExists(db, c.table, c.column)
+		if err != nil {
+			return err
+		}
+		if exists {
+			continue
+		}
+		if _, err = db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", c.table, c.column, c.definition)); err != nil {
+			return fmt.Errorf("storage: add column %s.%s: %w", c.table, c.column, err)
+		}
+		if c.fill != nil {
+			if err = c.fill(db); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// columnExists reports whether table has a column with the given name.
+func columnExists(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return false, fmt.Errorf("storage: table info %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			cid, notNull, pk int
+			name, colType    string
+			defaultValue     sql.NullString
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			return false, fmt.Errorf("storage: table info %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
+
 const userTable = `CREATE TABLE IF NOT EXISTS user (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	email TEXT UNIQUE,
 	username TEXT UNIQUE,
 	password TEXT,
-	token TEXT DEFAULT NULL,
-	expiresAt DATETIME DEFAULT NULL
+	role TEXT NOT NULL DEFAULT 'user',
+	createdAt DATETIME,
+	updatedAt DATETIME,
+	skeleton TEXT NOT NULL DEFAULT ''
+);`
+
+const sessionTable = `CREATE TABLE IF NOT EXISTS session (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid INTEGER NOT NULL,
+	token TEXT UNIQUE NOT NULL,
+	userAgent TEXT,
+	ip TEXT,
+	createdAt DATETIME,
+	lastSeenAt DATETIME,
+	expiresAt DATETIME,
+	FOREIGN KEY (userid) REFERENCES user(id) ON DELETE CASCADE
+);`
+
+const accessTokenTable = `CREATE TABLE IF NOT EXISTS access_token (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	userid INTEGER NOT NULL,
+	name TEXT,
+	tokenHash TEXT UNIQUE NOT NULL,
+	scopes TEXT,
+	createdAt DATETIME,
+	lastUsedAt DATETIME,
+	FOREIGN KEY (userid) REFERENCES user(id) ON DELETE CASCADE
 );`
 
 const postTable = `CREATE TABLE IF NOT EXISTS post (
@@ -45,13 +355,72 @@ const postTable = `CREATE TABLE IF NOT EXISTS post (
 	category TEXT,
 	like INTEGER DEFAULT 0,
 	dislike INTEGER DEFAULT 0,
-	userliked INTEGER Default 0
+	userliked INTEGER Default 0,
+	hidden INTEGER NOT NULL DEFAULT 0,
+	createdAt DATETIME,
+	updatedAt DATETIME,
+	commentCount INTEGER NOT NULL DEFAULT 0
+);`
+
+const postRevisionTable = `CREATE TABLE IF NOT EXISTS post_revision (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	postid INTEGER NOT NULL,
+	title TEXT NOT NULL,
+	content TEXT NOT NULL,
+	editorid INTEGER NOT NULL,
+	editedAt DATETIME NOT NULL
+);`
+
+const categoryTable = `CREATE TABLE IF NOT EXISTS category (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	slug TEXT UNIQUE NOT NULL,
+	name TEXT UNIQUE NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	position INTEGER NOT NULL DEFAULT 0,
+	archived INTEGER NOT NULL DEFAULT 0
+);`
+
+const categorySubscriptionTable = `CREATE TABLE IF NOT EXISTS category_subscription (
+	userid INTEGER NOT NULL,
+	categoryid INTEGER NOT NULL,
+	createdAt DATETIME,
+	PRIMARY KEY (userid, categoryid),
+	FOREIGN KEY (userid) REFERENCES user(id) ON DELETE CASCADE,
+	FOREIGN KEY (categoryid) REFERENCES category(id)
 );`
 
 const postCategoryTable = `CREATE TABLE IF NOT EXISTS post_category (
 	postID INTEGER,
-	category TEXT,
-	FOREIGN KEY (postID) REFERENCES post(id) ON DELETE CASCADE
+	categoryid INTEGER,
+	FOREIGN KEY (postID) REFERENCES post(id) ON DELETE CASCADE,
+	FOREIGN KEY (categoryid) REFERENCES category(id)
+);`
+
+const tagTable = `CREATE TABLE IF NOT EXISTS tag (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name TEXT UNIQUE NOT NULL
+);`
+
+const postTagTable = `CREATE TABLE IF NOT EXISTS post_tag (
+	postid INTEGER NOT NULL,
+	tagid INTEGER NOT NULL,
+	PRIMARY KEY (postid, tagid),
+	FOREIGN KEY (postid) REFERENCES post(id) ON DELETE CASCADE,
+	FOREIGN KEY (tagid) REFERENCES tag(id)
+);`
+
+const attachmentTable = `CREATE TABLE IF NOT EXISTS attachment (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	postid INTEGER NOT NULL,
+	name TEXT NOT NULL,
+	contentType TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	width INTEGER NOT NULL DEFAULT 0,
+	height INTEGER NOT NULL DEFAULT 0,
+	fileKey TEXT NOT NULL,
+	thumbnailKey TEXT NOT NULL DEFAULT '',
+	createdAt DATETIME,
+	FOREIGN KEY (postid) REFERENCES post(id) ON DELETE CASCADE
 );`
 
 const commentTable = `CREATE TABLE IF NOT EXISTS comment (
@@ -60,19 +429,71 @@ const commentTable = `CREATE TABLE IF NOT EXISTS comment (
 	postid INTEGER,
 	text TEXT,
 	like INTEGER DEFAULT 0,
-	dislike INTEGER DEFAULT 0
+	dislike INTEGER DEFAULT 0,
+	hidden INTEGER NOT NULL DEFAULT 0,
+	parentid INTEGER NOT NULL DEFAULT 0,
+	deleted INTEGER NOT NULL DEFAULT 0,
+	createdAt DATETIME,
+	updatedAt DATETIME
 );`
 
-const likeTable = `CREATE TABLE IF NOT EXISTS like (
+const commentRevisionTable = `CREATE TABLE IF NOT EXISTS comment_revision (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	username TEXT,
-	postid INTEGER,
-	commentId INTEGER DEFAULT NULL
+	commentid INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	editorid INTEGER NOT NULL,
+	editedAt DATETIME NOT NULL
 );`
 
-const dislikeTable = `CREATE TABLE IF NOT EXISTS dislike(
+// reactionTable keeps the reactions to posts and, when commentId is set, to comments. Likes and dislikes are
+// the reactions 👍 and 👎, they used to have tables of their own, see migrateVotes.
+const reactionTable = `CREATE TABLE IF NOT EXISTS reaction (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
-	username TEXT,
+	username TEXT NOT NULL,
 	postid INTEGER,
-	commentId INTEGER DEFAULT NULL
+	commentId INTEGER,
+	emoji TEXT NOT NULL,
+	createdAt DATETIME
+);`
+
+const reportTable = `CREATE TABLE IF NOT EXISTS report (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	reporterid INTEGER NOT NULL,
+	postid INTEGER NOT NULL,
+	commentid INTEGER NOT NULL DEFAULT 0,
+	reason TEXT,
+	status TEXT NOT NULL DEFAULT 'open',
+	createdAt DATETIME,
+	moderatorid INTEGER NOT NULL DEFAULT 0,
+	resolvedAt DATETIME
 );`
+
+// searchTriggerNames lists the triggers of searchTables, the first one is created first.
+var searchTriggerNames = []string{"post_search_insert", "post_search_delete", "post_search_update", "comment_search_insert", "comment_search_delete", "comment_search_update"}
+
+// searchTables index the titles, about texts and contents of posts and the texts of comments.
+// The indexes read the text from the post and comment tables, the triggers tell them when it changes.
+var searchTables = []string{
+	`CREATE VIRTUAL TABLE IF NOT EXISTS post_search USING fts5(title, about, content, content='post', content_rowid='id', tokenize='porter unicode61');`,
+	`CREATE VIRTUAL TABLE IF NOT EXISTS comment_search USING fts5(text, content='comment', content_rowid='id', tokenize='porter unicode61');`,
+	`CREATE TRIGGER IF NOT EXISTS post_search_insert AFTER INSERT ON post BEGIN
+	INSERT INTO post_search (rowid, title, about, content) VALUES (new.id, new.title, new.about, new.content);
+END;`,
+	`CREATE TRIGGER IF NOT EXISTS post_search_delete AFTER DELETE ON post BEGIN
+	INSERT INTO post_search (post_search, rowid, title, about, content) VALUES ('delete', old.id, old.title, old.about, old.content);
+END;`,
+	`CREATE TRIGGER IF NOT EXISTS post_search_update AFTER UPDATE OF title, about, content ON post BEGIN
+	INSERT INTO post_search (post_search, rowid, title, about, content) VALUES ('delete', old.id, old.title, old.about, old.content);
+	INSERT INTO post_search (rowid, title, about, content) VALUES (new.id, new.title, new.about, new.content);
+END;`,
+	`CREATE TRIGGER IF NOT EXISTS comment_search_insert AFTER INSERT ON comment BEGIN
+	INSERT INTO comment_search (rowid, text) VALUES (new.id, new.text);
+END;`,
+	`CREATE TRIGGER IF NOT EXISTS comment_search_delete AFTER DELETE ON comment BEGIN
+	INSERT INTO comment_search (comment_search, rowid, text) VALUES ('delete', old.id, old.text);
+END;`,
+	`CREATE TRIGGER IF NOT EXISTS comment_search_update AFTER UPDATE OF text ON comment BEGIN
+	INSERT INTO comment_search (comment_search, rowid, text) VALUES ('delete', old.id, old.text);
+	INSERT INTO comment_search (rowid, text) VALUES (new.id, new.text);
+END;`,
+}
@@ -2,150 +2,318 @@ package repository
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"forum/internal/filestore"
 	"forum/internal/models"
-	"log"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// ErrPostKeyMismatch is returned when the position a listing starts after does not fit its sort order.
+var ErrPostKeyMismatch = errors.New("post key does not match the sort order")
+
 // PostItem is an interface that defines the methods for interacting with the post repository.
 type PostItem interface {
 	CreatePost(post *models.Post) error
-	GetAllPosts() (posts []models.Post, err error)
+	GetAllPosts(listing models.PostListing) ([]models.Post, *models.PostKey, error)
 	GetPostByID(id int) (models.Post, error)
-	GetPostsByCategory(category string) ([]models.Post, error)
-	GetCreatedPosts(userID int) ([]models.Post, error)
-	GetLikedPosts(username string) ([]models.Post, error)
+	GetPostsByCategory(categoryID int, listing models.PostListing) ([]models.Post, *models.PostKey, error)
+	GetCreatedPosts(userID int, listing models.PostListing) ([]models.Post, *models.PostKey, error)
+	GetLikedPosts(username string, listing models.PostListing) ([]models.Post, *models.PostKey, error)
+	GetSubscribedPosts(userID int, listing models.PostListing) ([]models.Post, *models.PostKey, error)
+	FilterPosts(filter models.PostFilter, listing models.PostListing) ([]models.Post, *models.PostKey, error)
 	GetCategoriesByPostID(postId int) ([]string, error)
-	UpdatePost(id, like, dislike int, title, content string) error
+	SetPostTags(postID int, tags []string) error
+	UpdatePost(id int, title, content string, tags []string, editorID int, editedAt time.Time) error
+	GetPostRevisions(postID int) ([]models.PostRevision, error)
 	DeletePost(id int) error
-	LikePost(username string, postid int) error
-	DisLikePost(username string, postid int) error
-	RemoveLikePost(id int) error
-	RemoveDisLikePost(id int) error
-	HasUserLiked(username string, postid int) error
-	HasUserDislike(username string, postid int) error
+	SetPostHidden(id int, hidden bool) error
+	ModeratePost(id int, action models.ReportStatus, moderatorID int, resolvedAt time.Time) error
 }
 
 // PostStorage is a struct that implements the PostItem interface.
+// It deletes the files attached to a post from files together with the post.
 type PostStorage struct {
-	db *sql.DB
+	db    *sql.DB
+	files filestore.Store
 }
 
 // NewPostSqlite returns a new instance of PostStorage.
-func NewPostSqlite(db *sql.DB) *PostStorage {
-	return &PostStorage{db: db}
+func NewPostSqlite(db *sql.DB, files filestore.Store) *PostStorage {
+	return &PostStorage{db: db, files: files}
+}
+
+// postAgeHours is how many hours before the reference time of a listing, put in place of :now, a post was created, plus two.
+// Posts from before creation times were recorded count as created at the unix epoch.
+const postAgeHours = `((julianday(:now, 'unixepoch') - julianday(COALESCE(post.createdAt, '1970-01-01'))) * 24 + 2)`
+
+// categorySeparator joins the categories of a post in postCategories, category names never contain it.
+const categorySeparator = "\x1f"
+
+// postCategories joins the names of the categories of a post into one column in navigation order, NULL when it has none.
+const postCategories = `(SELECT GROUP_CONCAT(name, char(31)) FROM (SELECT category.name FROM post_category
+	JOIN category ON category.id = post_category.categoryid
+	WHERE post_category.postId = post.id ORDER BY category.position, category.id))`
+
+// postTags joins the tags of a post into one column in alphabetical order like postCategories, NULL when it has none.
+const postTags = `(SELECT GROUP_CONCAT(name, char(31)) FROM (SELECT tag.name FROM post_tag
+	JOIN tag ON tag.id = post_tag.tagid
+	WHERE post_tag.postid = post.id ORDER BY tag.name))`
+
+// splitCategories splits a column read from postCategories or postTags.
+func splitCategories(joined sql.NullString) []string {
+	if !joined.Valid || joined.String == "" {
+		return nil
+	}
+	return strings.Split(joined.String, categorySeparator)
+}
+
+// postSortKeys holds the sort keys of every sort order, the post ID always breaks ties.
+// The keys of top, discussed and controversial are the columns of an index, see indexes, so that a page is read
+// from the index rather than by sorting every post. Hot depends on the time of the listing and cannot be indexed.
+// Hot divides the score of a post by the square of its age, so that new posts with a few likes rise above
+// old posts with many.
+var postSortKeys = map[models.PostSort][]string{
+	models.SortNew:           nil,
+	models.SortTop:           {`post.like`},
+	models.SortDiscussed:     {`post.commentCount`},
+	models.SortControversial: {`MIN(post.like, post.dislike)`, `post.like + post.dislike`},
+	models.SortHot:           {`CAST(post.like - post.dislike + 1 AS REAL) / ` + postAgeHours + ` / ` + postAgeHours},
+}
+
+// listPosts returns a page of the visible posts matching filter, which may use the args,
+// together with the position of its last post when more posts follow.
+// The categories, tags and comment counts of the posts are read by the same query.
+func (p *PostStorage) listPosts(filter string, args []any, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	sortKeys, ok := postSortKeys[listing.Sort]
+	if !ok {
+		sortKeys = postSortKeys[models.SortNew]
+	}
+	keys := make([]string, len(sortKeys))
+	for i, key := range sortKeys {
+		keys[i] = strings.ReplaceAll(key, ":now", strconv.FormatInt(listing.Now, 10))
+	}
+
+	columns := append(keys[:len(keys):len(keys)], "post.id")
+
+	query := `SELECT id, userid, title, content, about, like, dislike, createdAt, updatedAt, ` + postCategories + `, ` + postTags + `, commentCount`
+	for _, key := range keys {
+		query += ", " + key
+	}
+	query += ` FROM post WHERE hidden = 0`
+	if filter != "" {
+		query += " AND " + filter
+	}
+
+	if listing.After != nil {
+		if len(listing.After.Values) != len(keys) {
+			return nil, nil, fmt.Errorf("storage: list posts: %w", ErrPostKeyMismatch)
+		}
+		// The bound on the first key alone is implied by the row value comparison, but it is what lets SQLite
+		// start reading the index of the order at the position, expression indexes are not searched by row values.
+		if len(keys) > 0 {
+			query += " AND " + keys[0] + " <= ?"
+			args = append(args, listing.After.Values[0])
+		}
+		query += " AND (" + strings.Join(columns, ", ") + ") < (" + strings.Repeat("?, ", len(keys)) + "?)"
+		for _, value := range listing.After.Values {
+			args = append(args, value)
+		}
+		args = append(args, listing.After.ID)
+	}
+
+	query += " ORDER BY " + strings.Join(columns, " DESC, ") + " DESC LIMIT ?"
+	args = append(args, listing.Limit+1)
+
+	rows, err := p.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("storage: list posts: %w", err)
+	}
+	defer rows.Close()
+
+	var (
+		posts []models.Post
+		last  models.PostKey
+		next  *models.PostKey
+	)
+	for rows.Next() {
+		if len(posts) == listing.Limit {
+			next = &last
+			break
+		}
+
+		var (
+			post           models.Post
+			category, tags sql.NullString
+		)
+		values := make([]float64, len(keys))
+		dest := []any{&post.Id, &post.UserID, &post.Title, &post.Content, &post.About, &post.Like, &post.DisLike, &post.CreatedAt, &post.UpdatedAt, &category, &tags, &post.Comments}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err = rows.Scan(dest...); err != nil {
+			return nil, nil, fmt.Errorf("storage: list posts: %w", err)
+		}
+		post.Category = splitCategories(category)
+		post.Tags = splitCategories(tags)
+
+		posts = append(posts, post)
+		last = models.PostKey{Values: values, ID: post.Id}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("storage: list posts: %w", err)
+	}
+
+	return posts, next, nil
 }
 
 // CreatePost creates a new post in the database.
 func (p *PostStorage) CreatePost(post *models.Post) error {
-	query := fmt.Sprintf(`INSERT INTO post (userid, title, content, about) values ($1, $2, $3, $4)`)
-	result, err := p.db.Exec(query, post.UserID, post.Title, post.Content, post.About)
+	query := fmt.Sprintf(`INSERT INTO post (userid, title, content, about, createdAt, updatedAt) values ($1, $2, $3, $4, $5, $6)`)
+	result, err := p.db.Exec(query, post.UserID, post.Title, post.Content, post.About, post.CreatedAt, post.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("storage: create post: %w", err)
 	}
 	postId, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create post: %w", err)
+	}
+	post.Id = int(postId)
 
-	query = `INSERT INTO post_category (postId, category) VALUES ($1, $2);`
+	query = `INSERT INTO post_category (postId, categoryid) SELECT $1, id FROM category WHERE name = $2;`
 	for _, oneCategory := range post.Category {
 		_, err := p.db.Exec(query, postId, oneCategory)
 		if err != nil {
 			return fmt.Errorf("storage: create post: %w", err)
 		}
 	}
-	return nil
+	return p.SetPostTags(post.Id, post.Tags)
 }
 
-// GetAllPosts returns all posts from the database.
-func (p *PostStorage) GetAllPosts() ([]models.Post, error) {
-	var posts []models.Post
-	rows, err := p.db.Query("SELECT id, userid, title, content, about FROM post")
+// SetPostTags replaces the tags of a post, tags that no post carried before are added.
+func (p *PostStorage) SetPostTags(postID int, tags []string) error {
+	tx, err := p.db.Begin()
 	if err != nil {
-		return nil, fmt.Errorf("storage: get all posts: query - %w", err)
+		return fmt.Errorf("storage: set post tags: %w", err)
 	}
+	defer tx.Rollback()
 
-	for rows.Next() {
-		p := models.Post{}
-		if err = rows.Scan(&p.Id, &p.UserID, &p.Title, &p.Content, &p.About); err != nil {
-			return posts, err
-		}
-		posts = append(posts, p)
+	if err = setPostTags(tx, postID, tags); err != nil {
+		return fmt.Errorf("storage: set post tags: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: set post tags: %w", err)
 	}
-	rows.Close()
-	return posts, nil
+	return nil
 }
 
-// GetPostsByCategory returns all posts that belong to a specific category.
-func (s *PostStorage) GetPostsByCategory(category string) ([]models.Post, error) {
-	var p []models.Post
-	query := `SELECT id, userid, title, content, about, like, dislike FROM post WHERE id IN (SELECT postId FROM post_category WHERE category=$1);`
-	rows, err := s.db.Query(query, category)
-	if err != nil {
-		return nil, fmt.Errorf("storage: get post by category: %w", err)
+// setPostTags replaces the tags of a post in tx.
+func setPostTags(tx *sql.Tx, postID int, tags []string) error {
+	if _, err := tx.Exec(`DELETE FROM post_tag WHERE postid = $1;`, postID); err != nil {
+		return err
 	}
-	for rows.Next() {
-		var post models.Post
-		if err := rows.Scan(&post.Id, &post.UserID, &post.Title, &post.Content, &post.About, &post.Like, &post.DisLike); err != nil {
-			return nil, fmt.Errorf("storage: get post by category: %w", err)
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO tag (name) VALUES ($1);`, tag); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO post_tag (postid, tagid) SELECT $1, id FROM tag WHERE name = $2;`, postID, tag); err != nil {
+			return err
 		}
-		p = append(p, post)
 	}
-	return p, nil
+	return nil
 }
 
-// GetCreatedPosts returns all posts created by a specific user.
-func (p *PostStorage) GetCreatedPosts(userID int) ([]models.Post, error) {
-	var posts []models.Post
-	rows, err := p.db.Query("SELECT id, userid, title, content, about FROM post WHERE userid=$1", userID)
-	if err != nil {
-		return nil, err
-	}
+// GetAllPosts returns a page of all posts.
+func (p *PostStorage) GetAllPosts(listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	return p.listPosts("", nil, listing)
+}
 
-	for rows.Next() {
-		p := models.Post{}
-		if err := rows.Scan(&p.Id, &p.UserID, &p.Title, &p.Content, &p.About); err != nil {
-			return posts, err
-		}
-		posts = append(posts, p)
-	}
-	rows.Close()
-	return posts, nil
+// GetPostsByCategory returns a page of the posts that belong to a specific category.
+func (p *PostStorage) GetPostsByCategory(categoryID int, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	return p.listPosts(`id IN (SELECT postId FROM post_category WHERE categoryid = ?)`, []any{categoryID}, listing)
 }
 
-// GetLikedPosts returns all posts liked by a specific user.
-func (p *PostStorage) GetLikedPosts(username string) ([]models.Post, error) {
-	var posts []models.Post
-	rows, err := p.db.Query("SELECT id, userid, title, content, about FROM post WHERE id IN (SELECT postid FROM like WHERE username=$1);", username)
-	if err != nil {
-		return nil, err
-	}
-	for rows.Next() {
-		p := models.Post{}
-		if err := rows.Scan(&p.Id, &p.UserID, &p.Title, &p.Content, &p.About); err != nil {
-			return posts, err
+// GetCreatedPosts returns a page of the posts created by a specific user.
+func (p *PostStorage) GetCreatedPosts(userID int, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	return p.listPosts(`userid = ?`, []any{userID}, listing)
+}
+
+// postLikedBy selects the posts that the user given as the first argument gave the reaction given as the second one,
+// which is the like.
+const postLikedBy = `id IN (SELECT postid FROM reaction WHERE username = ? AND emoji = ? AND commentId IS NULL)`
+
+// GetLikedPosts returns a page of the posts liked by a specific user.
+func (p *PostStorage) GetLikedPosts(username string, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	return p.listPosts(postLikedBy, []any{username, models.ReactionLike}, listing)
+}
+
+// GetSubscribedPosts returns a page of the posts filed under the categories that a specific user subscribed to.
+func (p *PostStorage) GetSubscribedPosts(userID int, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	return p.listPosts(`id IN (SELECT post_category.postId FROM post_category
+		JOIN category_subscription ON category_subscription.categoryid = post_category.categoryid
+		WHERE category_subscription.userid = ?)`, []any{userID}, listing)
+}
+
+// FilterPosts returns a page of the posts that match every condition of a filter.
+func (p *PostStorage) FilterPosts(filter models.PostFilter, listing models.PostListing) ([]models.Post, *models.PostKey, error) {
+	var (
+		conditions []string
+		args       []any
+	)
+	if filter.MatchAll {
+		// One condition per category lets every one of them use the post_category index.
+		for _, id := range filter.CategoryIDs {
+			conditions = append(conditions, `id IN (SELECT postId FROM post_category WHERE categoryid = ?)`)
+			args = append(args, id)
+		}
+	} else if len(filter.CategoryIDs) > 0 {
+		conditions = append(conditions, `id IN (SELECT postId FROM post_category WHERE categoryid IN (?`+strings.Repeat(", ?", len(filter.CategoryIDs)-1)+`))`)
+		for _, id := range filter.CategoryIDs {
+			args = append(args, id)
 		}
-		posts = append(posts, p)
 	}
-
-	rows.Close()
-	return posts, nil
+	if filter.CreatedBy != 0 {
+		conditions = append(conditions, `userid = ?`)
+		args = append(args, filter.CreatedBy)
+	}
+	if filter.LikedBy != "" {
+		conditions = append(conditions, postLikedBy)
+		args = append(args, filter.LikedBy, models.ReactionLike)
+	}
+	for _, tag := range filter.Tags {
+		conditions = append(conditions, `id IN (SELECT post_tag.postid FROM post_tag JOIN tag ON tag.id = post_tag.tagid WHERE tag.name = ?)`)
+		args = append(args, tag)
+	}
+	return p.listPosts(strings.Join(conditions, " AND "), args, listing)
 }
 
-// GetPostByID returns a post with a specific ID.
+// GetPostByID returns a post with a specific ID together with its categories, tags and comment count.
 func (p *PostStorage) GetPostByID(id int) (models.Post, error) {
-	query := `SELECT id, title, content, like, dislike FROM post WHERE id=$1;`
+	query := `SELECT id, userid, title, content, about, like, dislike, hidden, createdAt, updatedAt,
+		EXISTS (SELECT 1 FROM post_revision WHERE post_revision.postid = post.id), ` + postCategories + `, ` + postTags + `, commentCount
+		FROM post WHERE id=$1;`
 	row := p.db.QueryRow(query, id)
-	var post models.Post
-	err := row.Scan(&post.Id, &post.Title, &post.Content, &post.Like, &post.DisLike)
+	var (
+		post           models.Post
+		category, tags sql.NullString
+	)
+	err := row.Scan(&post.Id, &post.UserID, &post.Title, &post.Content, &post.About, &post.Like, &post.DisLike, &post.Hidden, &post.CreatedAt, &post.UpdatedAt, &post.Edited, &category, &tags, &post.Comments)
 	if err != nil {
-		return models.Post{}, fmt.Errorf("storage: get user by login: %w", err)
+		return models.Post{}, fmt.Errorf("storage: get post by id: %w", err)
 	}
+	post.Category = splitCategories(category)
+	post.Tags = splitCategories(tags)
 
 	return post, nil
 }
 
 // GetCategoriesByPostID returns all categories that a post belongs to.
 func (s *PostStorage) GetCategoriesByPostID(postId int) ([]string, error) {
-	queryCategory := `SELECT category FROM post_category where postId=$1;`
+	queryCategory := `SELECT category.name FROM post_category JOIN category ON category.id = post_category.categoryid
+		WHERE post_category.postId = $1 ORDER BY category.position, category.id;`
 	categoryRows, err := s.db.Query(queryCategory, postId)
 	if err != nil {
 		return nil, fmt.Errorf("storage: get all category by post id: %w", err)
@@ -161,127 +329,185 @@ func (s *PostStorage) GetCategoriesByPostID(postId int) ([]string, error) {
 	return category, nil
 }
 
-// UpdatePost updates a post with new information.
-func (p *PostStorage) UpdatePost(id, like, dislike int, title, content string) error {
-	query, err := p.db.Prepare(`UPDATE post SET title=?, content=?, like=?, dislike=? WHERE id=?;`)
+// UpdatePost changes the title and content of a post and keeps the previous version as a revision, and replaces its
+// tags unless tags is nil. A title and content that did not change leave no revision.
+// Everything happens in one transaction, so that the history neither loses nor repeats a version and an edit is
+// never saved with only some of its changes.
+func (p *PostStorage) UpdatePost(id int, title, content string, tags []string, editorID int, editedAt time.Time) error {
+	tx, err := p.db.Begin()
 	if err != nil {
-		log.Fatal(err)
+		return fmt.Errorf("storage: update post: %w", err)
 	}
-	_, err = query.Exec(title, content, like, dislike, id)
-	if err != nil {
-		fmt.Println("update", err)
-		return err
+	defer tx.Rollback()
+
+	query := `INSERT INTO post_revision (postid, title, content, editorid, editedAt) SELECT id, title, content, ?, ? FROM post
+		WHERE id = ? AND (title != ? OR content != ?);`
+	if _, err = tx.Exec(query, editorID, editedAt, id, title, content); err != nil {
+		return fmt.Errorf("storage: update post: %w", err)
 	}
 
-	return nil
-}
+	query = `UPDATE post SET title=$1, content=$2, updatedAt=$3 WHERE id=$4 AND (title != $1 OR content != $2);`
+	if _, err = tx.Exec(query, title, content, editedAt, id); err != nil {
+		return fmt.Errorf("storage: update post: %w", err)
+	}
 
-// DeletePost deletes a post with a specific ID.
-func (p *PostStorage) DeletePost(id int) error {
-	query := `DELETE FROM post WHERE id=?`
-	_, err := p.db.Exec(query, id)
-	if err != nil {
-		fmt.Println("delete", err)
-		return err
+	if tags != nil {
+		if err = setPostTags(tx, id, tags); err != nil {
+			return fmt.Errorf("storage: update post: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: update post: %w", err)
 	}
 	return nil
 }
 
-// LikePost adds a like to a post by a specific user.
-func (p *PostStorage) LikePost(username string, postid int) error {
-	query := `INSERT INTO like (username, postid) values ($1, $2)`
-
-	_, err := p.db.Exec(query, username, postid)
+// GetPostRevisions returns the earlier versions of a post, the oldest first.
+func (p *PostStorage) GetPostRevisions(postID int) ([]models.PostRevision, error) {
+	query := `SELECT post_revision.id, post_revision.postid, post_revision.title, post_revision.content,
+		post_revision.editorid, COALESCE(user.username, ''), post_revision.editedAt
+		FROM post_revision LEFT JOIN user ON user.id = post_revision.editorid
+		WHERE post_revision.postid = $1 ORDER BY post_revision.id;`
+	rows, err := p.db.Query(query, postID)
 	if err != nil {
-		return fmt.Errorf("repository: like post: Insert query - %w", err)
+		return nil, fmt.Errorf("storage: get post revisions: %w", err)
 	}
+	defer rows.Close()
 
-	query = `UPDATE post SET like = like + 1 WHERE id = $1;`
-
-	_, err = p.db.Exec(query, postid)
-	if err != nil {
-		return fmt.Errorf("repository: like post: Insert query - %w", err)
+	var revisions []models.PostRevision
+	for rows.Next() {
+		var r models.PostRevision
+		if err = rows.Scan(&r.ID, &r.PostID, &r.Title, &r.Content, &r.EditorID, &r.Editor, &r.EditedAt); err != nil {
+			return nil, fmt.Errorf("storage: get post revisions: %w", err)
+		}
+		revisions = append(revisions, r)
 	}
-
-	return nil
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage: get post revisions: %w", err)
+	}
+	return revisions, nil
 }
 
-// DisLikePost adds a dislike to a post by a specific user.
-func (p *PostStorage) DisLikePost(username string, postid int) error {
-	query := `INSERT INTO dislike (username, postid) values ($1, $2)`
-
-	_, err := p.db.Exec(query, username, postid)
+// DeletePost deletes a post with a specific ID together with everything that belongs to it: its categories, tags,
+// revisions, attachments, reactions and open reports, and its comments with their revisions, reactions and open
+// reports. Resolved reports are kept for the moderation log. Foreign keys are not enforced, so the rows are
+// deleted one table at a time in a single transaction.
+// The files of the attachments are deleted once the post is gone from the database.
+func (p *PostStorage) DeletePost(id int) error {
+	keys, err := p.attachmentKeys(id)
 	if err != nil {
-		return fmt.Errorf("repository: dislike post: Insert query - %w", err)
+		return fmt.Errorf("storage: delete post: %w", err)
 	}
 
-	query = `UPDATE post SET dislike = dislike + 1 WHERE id = $1;`
-
-	_, err = p.db.Exec(query, postid)
+	tx, err := p.db.Begin()
 	if err != nil {
-		return fmt.Errorf("repository: dislike post: Insert query - %w", err)
+		return fmt.Errorf("storage: delete post: %w", err)
 	}
-	return err
-}
+	defer tx.Rollback()
 
-// RemoveLikePost removes a like from a post.
-func (p *PostStorage) RemoveLikePost(id int) error {
-	stmt, err := p.db.Prepare(`UPDATE post SET like = like - 1 WHERE id = $1;`)
-	if err != nil {
-		return fmt.Errorf("repository: remove like from post: Delete query - %w", err)
+	if err = deletePost(tx, id); err != nil {
+		return fmt.Errorf("storage: delete post: %w", err)
 	}
 
-	_, err = stmt.Exec(id)
-	if err != nil {
-		return fmt.Errorf("repository: remove like from post: Delete query - %w", err)
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: delete post: %w", err)
+	}
+
+	if err = deleteFiles(p.files, keys...); err != nil {
+		return fmt.Errorf("storage: delete post attachments: %w", err)
 	}
 	return nil
 }
 
-// RemoveDisLikePost removes a dislike from a post.
-func (p *PostStorage) RemoveDisLikePost(id int) error {
-	stmt, err := p.db.Prepare(`UPDATE post SET dislike = dislike - 1 WHERE id = $1;`)
-	if err != nil {
-		return fmt.Errorf("repository: remove dislike from post: Delete query - %w", err)
+// deletePost deletes the rows of a post and of everything that belongs to it in tx.
+func deletePost(tx *sql.Tx, id int) error {
+	queries := []string{
+		`DELETE FROM reaction WHERE postid = $1 OR commentId IN (SELECT id FROM comment WHERE postid = $1);`,
+		`DELETE FROM comment_revision WHERE commentid IN (SELECT id FROM comment WHERE postid = $1);`,
+		`DELETE FROM report WHERE postid = $1 AND status = '` + string(models.ReportOpen) + `';`,
+		`DELETE FROM comment WHERE postid = $1;`,
+		`DELETE FROM post_category WHERE postID = $1;`,
+		`DELETE FROM post_tag WHERE postid = $1;`,
+		`DELETE FROM post_revision WHERE postid = $1;`,
+		`DELETE FROM attachment WHERE postid = $1;`,
+		`DELETE FROM post WHERE id = $1;`,
 	}
-
-	_, err = stmt.Exec(id)
-	if err != nil {
-		return fmt.Errorf("repository: remove dislike from post: Update query - %w", err)
+	for _, query := range queries {
+		if _, err := tx.Exec(query, id); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-// HasUserLiked checks if a user has liked a post and removes the like if they have.
-func (p *PostStorage) HasUserLiked(username string, postid int) error {
-	var u string
-	query := `SELECT username FROM like WHERE postid=? AND username = $2`
-
-	if err := p.db.QueryRow(query, postid, username).Scan(&u); err != nil {
-		return fmt.Errorf("repository: post has like: %w", err)
+// attachmentKeys returns the keys of the files and thumbnails attached to a post.
+func (p *PostStorage) attachmentKeys(postID int) ([]string, error) {
+	rows, err := p.db.Query(`SELECT fileKey, thumbnailKey FROM attachment WHERE postid = $1;`, postID)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	query = `DELETE FROM like WHERE postid=? AND username = $2`
-	if _, err := p.db.Exec(query, postid, username); err != nil {
-		return err
+	var keys []string
+	for rows.Next() {
+		var file, thumbnail string
+		if err := rows.Scan(&file, &thumbnail); err != nil {
+			return nil, err
+		}
+		keys = append(keys, file, thumbnail)
 	}
+	return keys, rows.Err()
+}
 
+// SetPostHidden hides a post from every listing or makes it visible again.
+func (p *PostStorage) SetPostHidden(id int, hidden bool) error {
+	query := `UPDATE post SET hidden=$1 WHERE id=$2;`
+	if _, err := p.db.Exec(query, hidden, id); err != nil {
+		return fmt.Errorf("storage: set post hidden: %w", err)
+	}
 	return nil
 }
 
-// HasUserDislike checks if a user has disliked a post and removes the dislike if they have.
-func (p *PostStorage) HasUserDislike(username string, postid int) error {
-	var u string
-	query := `SELECT username FROM dislike WHERE postid=? AND username = $2`
+// ModeratePost hides or deletes a post for a moderator and closes the open reports on it and on its comments with
+// that action, in one transaction, so that the reports are never closed while the post stays up.
+func (p *PostStorage) ModeratePost(id int, action models.ReportStatus, moderatorID int, resolvedAt time.Time) error {
+	var keys []string
+	if action == models.ReportDeleted {
+		var err error
+		if keys, err = p.attachmentKeys(id); err != nil {
+			return fmt.Errorf("storage: moderate post: %w", err)
+		}
+	}
 
-	if err := p.db.QueryRow(query, postid, username).Scan(&u); err != nil {
-		return fmt.Errorf("repository: post has dislike: %w", err)
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage: moderate post: %w", err)
 	}
+	defer tx.Rollback()
 
-	query = `DELETE FROM dislike WHERE postid=? AND username = $2`
-	if _, err := p.db.Exec(query, postid, username); err != nil {
-		return err
+	if err = resolvePostReports(tx, id, action, moderatorID, resolvedAt); err != nil {
+		return fmt.Errorf("storage: moderate post: %w", err)
 	}
 
+	switch action {
+	case models.ReportHidden:
+		_, err = tx.Exec(`UPDATE post SET hidden=$1 WHERE id=$2;`, true, id)
+	case models.ReportDeleted:
+		err = deletePost(tx, id)
+	default:
+		err = fmt.Errorf("unknown action %q", action)
+	}
+	if err != nil {
+		return fmt.Errorf("storage: moderate post: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: moderate post: %w", err)
+	}
+
+	if err = deleteFiles(p.files, keys...); err != nil {
+		return fmt.Errorf("storage: delete post attachments: %w", err)
+	}
 	return nil
 }
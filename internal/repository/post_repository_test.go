@@ -0,0 +1,433 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"forum/internal/models"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// benchPosts is the number of posts in the benchmark database.
+const benchPosts = 50000
+
+// benchPageSize is the number of posts on a benchmarked page, the default page size of the service.
+const benchPageSize = 20
+
+var (
+	benchOnce sync.Once
+	benchDir  string
+	benchDB   *sql.DB
+	benchErr  error
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	if benchDB != nil {
+		benchDB.Close()
+	}
+	if benchDir != "" {
+		os.RemoveAll(benchDir)
+	}
+	os.Exit(code)
+}
+
+// seededDB returns a database with benchPosts posts, seeded the first time a benchmark asks for it.
+func seededDB(b *testing.B) *sql.DB {
+	b.Helper()
+	benchOnce.Do(func() {
+		benchDir, benchErr = os.MkdirTemp("", "forum-bench")
+		if benchErr != nil {
+			return
+		}
+		benchDB, benchErr = sql.Open("sqlite3", filepath.Join(benchDir, "bench.db"))
+		if benchErr != nil {
+			return
+		}
+		if benchErr = CreateTables(benchDB); benchErr != nil {
+			return
+		}
+		benchErr = seedPosts(benchDB)
+	})
+	if benchErr != nil {
+		b.Fatalf("seed database: %v", benchErr)
+	}
+	return benchDB
+}
+
+// testDB returns an empty database with the tables and categories of a new forum, closed when the test ends.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db")+"?_txlock=immediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err = CreateTables(db); err != nil {
+		t.Fatalf("create tables: %v", err)
+	}
+	return db
+}
+
+// createTestPost creates a post by user 1 filed under the given categories.
+func createTestPost(t *testing.T, storage *PostStorage, title string, categories ...string) models.Post {
+	t.Helper()
+	now := models.Timestamp{Time: time.Now()}
+	post := models.Post{UserID: 1, Title: title, Content: "content", About: "about", Category: categories, CreatedAt: now, UpdatedAt: now}
+	if err := storage.CreatePost(&post); err != nil {
+		t.Fatalf("create post %q: %v", title, err)
+	}
+	return post
+}
+
+// postIDs returns the IDs of posts in order.
+func postIDs(posts []models.Post) []int {
+	ids := make([]int, len(posts))
+	for i, post := range posts {
+		ids[i] = post.Id
+	}
+	return ids
+}
+
+// seedPosts fills db with benchPosts posts, each filed under one or two categories
+// and with a few comments and likes, and then counts the comments of the posts.
+func seedPosts(db *sql.DB) error {
+	categories, err := NewCategorySqlite(db).ListCategories(false)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	insertPost, err := tx.Prepare(`INSERT INTO post (userid, title, content, about, like, dislike, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertCategory, err := tx.Prepare(`INSERT INTO post_category (postId, categoryid) VALUES (?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertComment, err := tx.Prepare(`INSERT INTO comment (author, postid, text, createdAt, updatedAt) VALUES (?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	insertLike, err := tx.Prepare(`INSERT INTO reaction (username, postid, emoji) VALUES (?, ?, '👍')`)
+	if err != nil {
+		return err
+	}
+
+	random := rand.New(rand.NewSource(1))
+	start := time.Now().Add(-benchPosts * time.Minute)
+	for i := 0; i < benchPosts; i++ {
+		createdAt := start.Add(time.Duration(i) * time.Minute)
+		user := random.Intn(100) + 1
+		result, err := insertPost.Exec(user, fmt.Sprintf("Post %d", i), "content", "about", random.Intn(50), random.Intn(50), createdAt, createdAt)
+		if err != nil {
+			return err
+		}
+		postID, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		first := random.Intn(len(categories))
+		if _, err = insertCategory.Exec(postID, categories[first].ID); err != nil {
+			return err
+		}
+		if second := random.Intn(len(categories)); second != first {
+			if _, err = insertCategory.Exec(postID, categories[second].ID); err != nil {
+				return err
+			}
+		}
+
+		for c := random.Intn(4); c > 0; c-- {
+			if _, err = insertComment.Exec(fmt.Sprintf("user%d", random.Intn(100)+1), postID, "comment", createdAt, createdAt); err != nil {
+				return err
+			}
+		}
+
+		if random.Intn(10) == 0 {
+			if _, err = insertLike.Exec(fmt.Sprintf("user%d", user), postID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+	return countComments(db)
+}
+
+// benchListing returns the listing of the first page in the given order.
+func benchListing(sort models.PostSort) models.PostListing {
+	return models.PostListing{Sort: sort, Now: time.Now().Unix(), Limit: benchPageSize}
+}
+
+// BenchmarkGetAllPosts reads a page of posts with their categories and comment counts in one query.
+func BenchmarkGetAllPosts(b *testing.B) {
+	storage := NewPostSqlite(seededDB(b), nil)
+	for _, sort := range models.PostSorts {
+		b.Run(string(sort), func(b *testing.B) {
+			listing := benchListing(sort)
+			for i := 0; i < b.N; i++ {
+				if _, _, err := storage.GetAllPosts(listing); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetAllPostsPerPostCategories reads a page of posts and then the categories of every post
+// one query at a time, the way listings were read before categories were fetched with the page.
+func BenchmarkGetAllPostsPerPostCategories(b *testing.B) {
+	storage := NewPostSqlite(seededDB(b), nil)
+	for _, sort := range models.PostSorts {
+		b.Run(string(sort), func(b *testing.B) {
+			listing := benchListing(sort)
+			for i := 0; i < b.N; i++ {
+				posts, _, err := storage.GetAllPosts(listing)
+				if err != nil {
+					b.Fatal(err)
+				}
+				for j := range posts {
+					if posts[j].Category, err = storage.GetCategoriesByPostID(posts[j].Id); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkGetPostsByCategory reads a page of the posts filed under one category.
+func BenchmarkGetPostsByCategory(b *testing.B) {
+	storage := NewPostSqlite(seededDB(b), nil)
+	listing := benchListing(models.SortNew)
+	for i := 0; i < b.N; i++ {
+		if _, _, err := storage.GetPostsByCategory(1, listing); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkFilterPostsAllCategories reads a page of the posts filed under both of two categories.
+func BenchmarkFilterPostsAllCategories(b *testing.B) {
+	storage := NewPostSqlite(seededDB(b), nil)
+	listing := benchListing(models.SortNew)
+	filter := models.PostFilter{CategoryIDs: []int{1, 2}, MatchAll: true}
+	for i := 0; i < b.N; i++ {
+		if _, _, err := storage.FilterPosts(filter, listing); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkGetAllPostsLastPage reads the last page of every sort order, which the keyset condition reaches
+// through the index of the order without scanning the pages before it. Hot cannot be indexed and sorts every post.
+func BenchmarkGetAllPostsLastPage(b *testing.B) {
+	storage := NewPostSqlite(seededDB(b), nil)
+	for _, sort := range models.PostSorts {
+		b.Run(string(sort), func(b *testing.B) {
+			listing := benchListing(sort)
+			listing.Limit = benchPosts - benchPageSize
+			_, after, err := storage.GetAllPosts(listing)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if after == nil {
+				b.Fatal("no page after the first benchPosts - benchPageSize posts")
+			}
+			listing.Limit = benchPageSize
+			listing.After = after
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, _, err := storage.GetAllPosts(listing); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestListPostsPages checks that following the position of the last post from page to page, sent through JSON
+// the way cursors carry it, lists every visible post exactly once and in the order of a single page, also
+// when many posts tie on the sort keys.
+func TestListPostsPages(t *testing.T) {
+	db := testDB(t)
+	storage := NewPostSqlite(db, nil)
+	for i := 0; i < 23; i++ {
+		post := createTestPost(t, storage, fmt.Sprintf("Post %d", i))
+		createdAt := time.Now().Add(time.Duration(i-23) * time.Hour)
+		_, err := db.Exec(`UPDATE post SET like = ?, dislike = ?, commentCount = ?, createdAt = ? WHERE id = ?`,
+			i%3, i%4, i%5, createdAt, post.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	hidden := createTestPost(t, storage, "Hidden")
+	if err := storage.SetPostHidden(hidden.Id, true); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sort := range models.PostSorts {
+		t.Run(string(sort), func(t *testing.T) {
+			listing := models.PostListing{Sort: sort, Now: time.Now().Unix(), Limit: 100}
+			all, next, err := storage.GetAllPosts(listing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 23 || next != nil {
+				t.Fatalf("single page has %d posts and next %v, want 23 posts and no next", len(all), next)
+			}
+
+			var paged []int
+			listing.Limit = 4
+			for pages := 0; ; pages++ {
+				if pages > 23 {
+					t.Fatal("paging does not end")
+				}
+				posts, next, err := storage.GetAllPosts(listing)
+				if err != nil {
+					t.Fatal(err)
+				}
+				paged = append(paged, postIDs(posts)...)
+				if next == nil {
+					break
+				}
+				if len(posts) != listing.Limit {
+					t.Fatalf("page of %d posts is followed by another one", len(posts))
+				}
+
+				raw, err := json.Marshal(next)
+				if err != nil {
+					t.Fatal(err)
+				}
+				listing.After = new(models.PostKey)
+				if err = json.Unmarshal(raw, listing.After); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if want := postIDs(all); !reflect.DeepEqual(paged, want) {
+				t.Errorf("pages list %v, want %v", paged, want)
+			}
+		})
+	}
+}
+
+// TestListPostsKeyMismatch checks that a position with another number of sort keys than the order is refused.
+func TestListPostsKeyMismatch(t *testing.T) {
+	storage := NewPostSqlite(testDB(t), nil)
+	createTestPost(t, storage, "Post")
+
+	for _, sort := range models.PostSorts {
+		keys := len(postSortKeys[sort])
+		for _, values := range [][]float64{make([]float64, keys+1), make([]float64, keys+2)} {
+			listing := models.PostListing{Sort: sort, Now: time.Now().Unix(), Limit: 10, After: &models.PostKey{Values: values, ID: 5}}
+			if _, _, err := storage.GetAllPosts(listing); !errors.Is(err, ErrPostKeyMismatch) {
+				t.Errorf("%s after %d keys: err = %v, want ErrPostKeyMismatch", sort, len(values), err)
+			}
+		}
+		if keys > 0 {
+			listing := models.PostListing{Sort: sort, Now: time.Now().Unix(), Limit: 10, After: &models.PostKey{ID: 5}}
+			if _, _, err := storage.GetAllPosts(listing); !errors.Is(err, ErrPostKeyMismatch) {
+				t.Errorf("%s after no keys: err = %v, want ErrPostKeyMismatch", sort, err)
+			}
+		}
+	}
+}
+
+// TestFilterPostsCategories checks the posts kept by a filter on any and on all of its categories.
+func TestFilterPostsCategories(t *testing.T) {
+	db := testDB(t)
+	categories, err := NewCategorySqlite(db).ListCategories(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(categories) < 3 {
+		t.Fatalf("%d categories, want at least 3", len(categories))
+	}
+	a, b, c := categories[0], categories[1], categories[2]
+
+	storage := NewPostSqlite(db, nil)
+	onlyA := createTestPost(t, storage, "A", a.Name)
+	onlyB := createTestPost(t, storage, "B", b.Name)
+	both := createTestPost(t, storage, "A and B", a.Name, b.Name)
+	none := createTestPost(t, storage, "None")
+
+	tests := []struct {
+		name   string
+		filter models.PostFilter
+		want   []int
+	}{
+		{"no categories", models.PostFilter{}, []int{none.Id, both.Id, onlyB.Id, onlyA.Id}},
+		{"any of one", models.PostFilter{CategoryIDs: []int{a.ID}}, []int{both.Id, onlyA.Id}},
+		{"all of one", models.PostFilter{CategoryIDs: []int{a.ID}, MatchAll: true}, []int{both.Id, onlyA.Id}},
+		{"any of two", models.PostFilter{CategoryIDs: []int{a.ID, b.ID}}, []int{both.Id, onlyB.Id, onlyA.Id}},
+		{"all of two", models.PostFilter{CategoryIDs: []int{a.ID, b.ID}, MatchAll: true}, []int{both.Id}},
+		{"any with an empty category", models.PostFilter{CategoryIDs: []int{b.ID, c.ID}}, []int{both.Id, onlyB.Id}},
+		{"all with an empty category", models.PostFilter{CategoryIDs: []int{a.ID, c.ID}, MatchAll: true}, []int{}},
+		{"all and created by", models.PostFilter{CategoryIDs: []int{a.ID}, MatchAll: true, CreatedBy: 2}, []int{}},
+	}
+	for _, tt := range tests {
+		posts, next, err := storage.FilterPosts(tt.filter, models.PostListing{Sort: models.SortNew, Limit: 10})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := postIDs(posts); next != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: posts %v and next %v, want %v and no next", tt.name, got, next, tt.want)
+		}
+	}
+}
+
+// TestUpdatePostTags checks that an edit saves its text and tags together, keeps a revision only when the text
+// changes, and keeps the tags when none are given.
+func TestUpdatePostTags(t *testing.T) {
+	db := testDB(t)
+	storage := NewPostSqlite(db, nil)
+	post := createTestPost(t, storage, "Post")
+
+	steps := []struct {
+		name, title string
+		tags        []string
+		wantTags    []string
+		revisions   int
+	}{
+		{"text and tags", "Edited", []string{"go", "sql"}, []string{"go", "sql"}, 1},
+		{"tags only", "Edited", []string{"go"}, []string{"go"}, 1},
+		{"text only", "Edited again", nil, []string{"go"}, 2},
+		{"no tags", "Edited again", []string{}, nil, 2},
+	}
+	for _, step := range steps {
+		if err := storage.UpdatePost(post.Id, step.title, "content", step.tags, 1, time.Now()); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		got, err := storage.GetPostByID(post.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		revisions, err := storage.GetPostRevisions(post.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Title != step.title || !reflect.DeepEqual(got.Tags, step.wantTags) || len(revisions) != step.revisions {
+			t.Errorf("%s: title %q, tags %q and %d revisions, want %q, %q and %d", step.name,
+				got.Title, got.Tags, len(revisions), step.title, step.wantTags, step.revisions)
+		}
+	}
+}
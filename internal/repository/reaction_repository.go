@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"time"
+)
+
+// reactionUsers is how many of the users who reacted with an emoji are listed with its count.
+const reactionUsers = 10
+
+// ReactionItem is an interface that defines the methods for storing the reactions to posts and comments.
+type ReactionItem interface {
+	TogglePostReaction(username string, postID int, emoji string) error
+	ToggleCommentReaction(username string, commentID int, emoji string) error
+	GetPostReactions(postID int, viewer string) ([]models.Reaction, error)
+	GetCommentReactions(postID int, viewer string) (map[int][]models.Reaction, error)
+	GetPostReactionUsers(postID int, emoji string, limit, offset int) ([]models.ReactionUser, error)
+	GetCommentReactionUsers(commentID int, emoji string, limit, offset int) ([]models.ReactionUser, error)
+}
+
+// ReactionStorage is a struct that implements the ReactionItem interface.
+type ReactionStorage struct {
+	db *sql.DB
+}
+
+// NewReactionSqlite returns a new instance of ReactionStorage.
+func NewReactionSqlite(db *sql.DB) *ReactionStorage {
+	return &ReactionStorage{db: db}
+}
+
+// reactionTarget describes what users react to, posts or comments. Reactions to both are kept in the reaction
+// table, told apart by commentId, which only reactions to comments set.
+type reactionTarget struct {
+	// table keeps the targets together with their like and dislike counters.
+	table string
+	// column is the column of reaction that holds the id of the target.
+	column string
+	// comment is "IS NULL" for the reactions to posts and "IS NOT NULL" for the reactions to comments.
+	comment string
+	// visible holds for the rows of the target table that users can react to: neither hidden nor deleted,
+	// and for comments, on a post that is not hidden.
+	visible string
+}
+
+var (
+	postReactions    = reactionTarget{table: "post", column: "postid", comment: "IS NULL", visible: "hidden = 0"}
+	commentReactions = reactionTarget{table: "comment", column: "commentId", comment: "IS NOT NULL",
+		visible: "hidden = 0 AND deleted = 0 AND postid IN (SELECT id FROM post WHERE hidden = 0)"}
+)
+
+// reaction selects the reaction $3 of the user $2 to the target $1.
+func (t reactionTarget) reaction() string {
+	return fmt.Sprintf(`reaction WHERE %s = $1 AND commentId %s AND username = $2 AND emoji = $3`, t.column, t.comment)
+}
+
+// count selects the number of reactions with an emoji to the row of the target table being read or updated.
+func (t reactionTarget) count(emoji string) string {
+	return fmt.Sprintf(`(SELECT COUNT(*) FROM reaction WHERE reaction.%s = %s.id AND reaction.commentId %s AND reaction.emoji = '%s')`,
+		t.column, t.table, t.comment, emoji)
+}
+
+// recount sets the like and dislike counters of the target table to the number of likes and dislikes.
+func (t reactionTarget) recount() string {
+	return fmt.Sprintf(`UPDATE %s SET like = %s, dislike = %s`, t.table, t.count(models.ReactionLike), t.count(models.ReactionDislike))
+}
+
+// opposite returns the reaction that a like or a dislike replaces, and "" for the other reactions.
+func opposite(emoji string) string {
+	switch emoji {
+	case models.ReactionLike:
+		return models.ReactionDislike
+	case models.ReactionDislike:
+		return models.ReactionLike
+	}
+	return ""
+}
+
+// TogglePostReaction gives the reaction of a user to a post, or takes it back when it was given already.
+func (r *ReactionStorage) TogglePostReaction(username string, postID int, emoji string) error {
+	if err := r.toggle(postReactions, postID, username, emoji); err != nil {
+		return fmt.Errorf("storage: toggle post reaction: %w", err)
+	}
+	return nil
+}
+
+// ToggleCommentReaction gives the reaction of a user to a comment, or takes it back when it was given already.
+func (r *ReactionStorage) ToggleCommentReaction(username string, commentID int, emoji string) error {
+	if err := r.toggle(commentReactions, commentID, username, emoji); err != nil {
+		return fmt.Errorf("storage: toggle comment reaction: %w", err)
+	}
+	return nil
+}
+
+// toggle gives the reaction of a user to a post or comment, or takes it back when it was given already.
+// Giving a like takes back a dislike and the other way around. It all happens in one transaction, which ends by
+// recounting the likes and dislikes of the target, so that clicks at the same time can neither record a reaction
+// twice, which the unique indexes of reaction refuse, nor leave the counters off.
+// It returns sql.ErrNoRows when the target does not exist or is hidden or deleted.
+func (r *ReactionStorage) toggle(target reactionTarget, id int, username, emoji string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var visible int
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE id = $1 AND %s;`, target.table, target.visible)
+	if err = tx.QueryRow(query, id).Scan(&visible); err != nil {
+		return err
+	}
+
+	res, err := tx.Exec(`DELETE FROM `+target.reaction()+`;`, id, username, emoji)
+	if err != nil {
+		return err
+	}
+	removed, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		if other := opposite(emoji); other != "" {
+			if _, err = tx.Exec(`DELETE FROM `+target.reaction()+`;`, id, username, other); err != nil {
+				return err
+			}
+		}
+		query = fmt.Sprintf(`INSERT INTO reaction (%s, username, emoji, createdAt) VALUES ($1, $2, $3, $4);`, target.column)
+		if _, err = tx.Exec(query, id, username, emoji, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	if _, err = tx.Exec(target.recount()+` WHERE id = $1;`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetPostReactions returns the reactions to a post, in the order they were first given.
+// Reacted is set for the reactions of viewer.
+func (r *ReactionStorage) GetPostReactions(postID int, viewer string) ([]models.Reaction, error) {
+	reactions, err := r.list(postReactions, `postid = ?`, postID, viewer)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get post reactions: %w", err)
+	}
+	return reactions[postID], nil
+}
+
+// GetCommentReactions returns the reactions to the comments of a post by comment id, in the order they were first given.
+// Reacted is set for the reactions of viewer.
+func (r *ReactionStorage) GetCommentReactions(postID int, viewer string) (map[int][]models.Reaction, error) {
+	reactions, err := r.list(commentReactions, `commentId IN (SELECT id FROM comment WHERE postid = ?)`, postID, viewer)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get comment reactions: %w", err)
+	}
+	return reactions, nil
+}
+
+// list counts the reactions to the targets picked by the condition where, per target and emoji, together with
+// the first reactionUsers users who gave each of them.
+func (r *ReactionStorage) list(target reactionTarget, where string, arg any, viewer string) (map[int][]models.Reaction, error) {
+	query := fmt.Sprintf(`SELECT target, emoji, username, total, reacted FROM (
+		SELECT %[1]s AS target, emoji, username,
+			COUNT(*) OVER byEmoji AS total,
+			MAX(username = ?) OVER byEmoji AS reacted,
+			MIN(id) OVER byEmoji AS first,
+			ROW_NUMBER() OVER (byEmoji ORDER BY id) AS position
+		FROM reaction WHERE commentId %[2]s AND %[3]s
+		WINDOW byEmoji AS (PARTITION BY %[1]s, emoji)
+	) WHERE position <= ? ORDER BY target, first, position;`, target.column, target.comment, where)
+	rows, err := r.db.Query(query, viewer, arg, reactionUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := map[int][]models.Reaction{}
+	for rows.Next() {
+		var (
+			id              int
+			emoji, username string
+			total           int
+			reacted         bool
+		)
+		if err = rows.Scan(&id, &emoji, &username, &total, &reacted); err != nil {
+			return nil, err
+		}
+		list := reactions[id]
+		if len(list) == 0 || list[len(list)-1].Emoji != emoji {
+			list = append(list, models.Reaction{Emoji: emoji, Count: total, Reacted: reacted})
+		}
+		list[len(list)-1].Users = append(list[len(list)-1].Users, username)
+		reactions[id] = list
+	}
+	return reactions, rows.Err()
+}
+
+// GetPostReactionUsers returns the users who gave a reaction to a post, the latest first,
+// skipping offset of them and returning at most limit.
+func (r *ReactionStorage) GetPostReactionUsers(postID int, emoji string, limit, offset int) ([]models.ReactionUser, error) {
+	users, err := r.users(postReactions, postID, emoji, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get post reaction users: %w", err)
+	}
+	return users, nil
+}
+
+// GetCommentReactionUsers returns the users who gave a reaction to a comment, the latest first,
+// skipping offset of them and returning at most limit.
+func (r *ReactionStorage) GetCommentReactionUsers(commentID int, emoji string, limit, offset int) ([]models.ReactionUser, error) {
+	users, err := r.users(commentReactions, commentID, emoji, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get comment reaction users: %w", err)
+	}
+	return users, nil
+}
+
+func (r *ReactionStorage) users(target reactionTarget, id int, emoji string, limit, offset int) ([]models.ReactionUser, error) {
+	query := fmt.Sprintf(`SELECT username, createdAt FROM reaction WHERE %s = $1 AND commentId %s AND emoji = $2
+		ORDER BY id DESC LIMIT $3 OFFSET $4;`, target.column, target.comment)
+	rows, err := r.db.Query(query, id, emoji, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.ReactionUser
+	for rows.Next() {
+		var user models.ReactionUser
+		if err = rows.Scan(&user.Username, &user.ReactedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+// ReconcileReactions recomputes the like and dislike counters of every post and comment from the reaction table,
+// and returns how many posts and comments had counters that were off.
+func ReconcileReactions(db *sql.DB) (int64, error) {
+	var fixed int64
+	for _, target := range []reactionTarget{postReactions, commentReactions} {
+		likes, dislikes := target.count(models.ReactionLike), target.count(models.ReactionDislike)
+		res, err := db.Exec(target.recount() + ` WHERE like IS NOT ` + likes + ` OR dislike IS NOT ` + dislikes + `;`)
+		if err != nil {
+			return 0, fmt.Errorf("storage: reconcile reactions: %w", err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, fmt.Errorf("storage: reconcile reactions: %w", err)
+		}
+		fixed += n
+	}
+	return fixed, nil
+}
+
+// migrateVotes moves the likes and dislikes of older versions, which kept them in tables of their own, into the
+// reaction table and drops those tables. Older versions could record a vote twice, and both a like and a dislike
+// of one user on one post or comment: the unique indexes of reaction skip the repeated votes, and the like is kept
+// when both were given. The counters are recounted afterwards.
+func migrateVotes(db *sql.DB) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'like');`
+	if err := db.QueryRow(query).Scan(&exists); err != nil {
+		return fmt.Errorf("storage: migrate votes: %w", err)
+	}
+	if !exists {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("storage: migrate votes: %w", err)
+	}
+	defer tx.Rollback()
+
+	statements := []struct {
+		query string
+		args  []any
+	}{
+		{`INSERT OR IGNORE INTO reaction (postid, commentId, username, emoji)
+			SELECT CASE WHEN commentId IS NULL THEN postid END, commentId, username, $1 FROM like ORDER BY id;`,
+			[]any{models.ReactionLike}},
+		{`INSERT OR IGNORE INTO reaction (postid, commentId, username, emoji)
+			SELECT CASE WHEN commentId IS NULL THEN postid END, commentId, username, $1 FROM dislike
+			WHERE NOT EXISTS (SELECT 1 FROM reaction WHERE reaction.username = dislike.username AND reaction.emoji = $2
+				AND (reaction.commentId = dislike.commentId
+					OR (dislike.commentId IS NULL AND reaction.commentId IS NULL AND reaction.postid = dislike.postid)))
+			ORDER BY id;`,
+			[]any{models.ReactionDislike, models.ReactionLike}},
+		{`DROP TABLE like;`, nil},
+		{`DROP TABLE IF EXISTS dislike;`, nil},
+	}
+	for _, statement := range statements {
+		if _, err = tx.Exec(statement.query, statement.args...); err != nil {
+			return fmt.Errorf("storage: migrate votes: %w", err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("storage: migrate votes: %w", err)
+	}
+
+	if _, err = ReconcileReactions(db); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"forum/internal/models"
+)
+
+// createTestComment creates a comment by author on a post.
+func createTestComment(t *testing.T, db *sql.DB, postID int, author string) models.Comment {
+	t.Helper()
+	now := models.Timestamp{Time: time.Now()}
+	comment := models.Comment{PostID: postID, Author: author, Text: "comment", CreatedAt: now, UpdatedAt: now}
+	if err := NewCommentSqlite(db).CreateComment(&comment); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	return comment
+}
+
+// checkPostVotes fails the test unless the like and dislike counters of a post are likes and dislikes.
+func checkPostVotes(t *testing.T, storage *PostStorage, postID, likes, dislikes int) {
+	t.Helper()
+	post, err := storage.GetPostByID(postID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Like != likes || post.DisLike != dislikes {
+		t.Errorf("post has %d likes and %d dislikes, want %d and %d", post.Like, post.DisLike, likes, dislikes)
+	}
+}
+
+func TestTogglePostReaction(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	reactions := NewReactionSqlite(db)
+	post := createTestPost(t, posts, "Post")
+
+	steps := []struct {
+		username, emoji string
+		likes, dislikes int
+	}{
+		{"alice", models.ReactionLike, 1, 0},
+		{"bob", models.ReactionLike, 2, 0},
+		{"alice", models.ReactionLike, 1, 0},
+		{"alice", models.ReactionDislike, 1, 1},
+		{"alice", models.ReactionLike, 2, 0},
+		{"bob", models.ReactionDislike, 1, 1},
+		{"bob", "🎉", 1, 1},
+		{"bob", models.ReactionDislike, 1, 0},
+	}
+	for _, step := range steps {
+		if err := reactions.TogglePostReaction(step.username, post.Id, step.emoji); err != nil {
+			t.Fatalf("%s toggles %s: %v", step.username, step.emoji, err)
+		}
+		checkPostVotes(t, posts, post.Id, step.likes, step.dislikes)
+	}
+
+	got, err := reactions.GetPostReactions(post.Id, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.Reaction{
+		{Emoji: models.ReactionLike, Count: 1, Users: []string{"alice"}},
+		{Emoji: "🎉", Count: 1, Users: []string{"bob"}, Reacted: true},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("reactions = %v, want %v", got, want)
+	}
+}
+
+// TestTogglePostReactionConcurrent checks that likes given at the same time are all recorded and counted.
+func TestTogglePostReactionConcurrent(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	reactions := NewReactionSqlite(db)
+	post := createTestPost(t, posts, "Post")
+
+	const users = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, users)
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(username string) {
+			defer wg.Done()
+			errs <- reactions.TogglePostReaction(username, post.Id, models.ReactionLike)
+		}(fmt.Sprintf("user%d", i))
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkPostVotes(t, posts, post.Id, users, 0)
+}
+
+// TestToggleReactionUnavailable checks that posts and comments that are missing, hidden or deleted take no reactions.
+func TestToggleReactionUnavailable(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	comments := NewCommentSqlite(db)
+	reactions := NewReactionSqlite(db)
+
+	hiddenPost := createTestPost(t, posts, "Hidden")
+	onHiddenPost := createTestComment(t, db, hiddenPost.Id, "alice")
+	if err := posts.SetPostHidden(hiddenPost.Id, true); err != nil {
+		t.Fatal(err)
+	}
+	post := createTestPost(t, posts, "Post")
+	hiddenComment := createTestComment(t, db, post.Id, "alice")
+	if err := comments.SetCommentHidden(hiddenComment.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	deletedComment := createTestComment(t, db, post.Id, "alice")
+	if err := comments.DeleteComment(deletedComment.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		toggle func() error
+	}{
+		{"missing post", func() error { return reactions.TogglePostReaction("bob", 1000, models.ReactionLike) }},
+		{"hidden post", func() error { return reactions.TogglePostReaction("bob", hiddenPost.Id, models.ReactionLike) }},
+		{"missing comment", func() error { return reactions.ToggleCommentReaction("bob", 1000, models.ReactionLike) }},
+		{"hidden comment", func() error { return reactions.ToggleCommentReaction("bob", hiddenComment.ID, models.ReactionLike) }},
+		{"deleted comment", func() error { return reactions.ToggleCommentReaction("bob", deletedComment.ID, models.ReactionLike) }},
+		{"comment on a hidden post", func() error { return reactions.ToggleCommentReaction("bob", onHiddenPost.ID, models.ReactionLike) }},
+	}
+	for _, tt := range tests {
+		if err := tt.toggle(); !errors.Is(err, sql.ErrNoRows) {
+			t.Errorf("%s: err = %v, want sql.ErrNoRows", tt.name, err)
+		}
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM reaction`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("%d reactions recorded, want 0", count)
+	}
+}
+
+// TestToggleCommentReaction checks that reactions to a comment are counted on the comment and not on its post.
+func TestToggleCommentReaction(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	comments := NewCommentSqlite(db)
+	reactions := NewReactionSqlite(db)
+	post := createTestPost(t, posts, "Post")
+	comment := createTestComment(t, db, post.Id, "alice")
+
+	for _, username := range []string{"alice", "bob"} {
+		if err := reactions.ToggleCommentReaction(username, comment.ID, models.ReactionDislike); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reactions.ToggleCommentReaction("alice", comment.ID, models.ReactionLike); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := comments.GetCommentByID(comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Likes != 1 || got.DisLikes != 1 {
+		t.Errorf("comment has %d likes and %d dislikes, want 1 and 1", got.Likes, got.DisLikes)
+	}
+	checkPostVotes(t, posts, post.Id, 0, 0)
+}
+
+// TestReconcileReactions checks that counters that were left off are recounted and that right ones are left alone.
+func TestReconcileReactions(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	comments := NewCommentSqlite(db)
+	reactions := NewReactionSqlite(db)
+
+	liked := createTestPost(t, posts, "Liked")
+	untouched := createTestPost(t, posts, "Untouched")
+	comment := createTestComment(t, db, liked.Id, "alice")
+	for _, username := range []string{"alice", "bob"} {
+		if err := reactions.TogglePostReaction(username, liked.Id, models.ReactionLike); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reactions.TogglePostReaction("carol", untouched.Id, models.ReactionDislike); err != nil {
+		t.Fatal(err)
+	}
+	if err := reactions.ToggleCommentReaction("bob", comment.ID, models.ReactionDislike); err != nil {
+		t.Fatal(err)
+	}
+
+	fixed, err := ReconcileReactions(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 0 {
+		t.Errorf("reconciling right counters fixed %d, want 0", fixed)
+	}
+
+	if _, err = db.Exec(`UPDATE post SET like = 7, dislike = 3 WHERE id = ?`, liked.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = db.Exec(`UPDATE comment SET dislike = 0 WHERE id = ?`, comment.ID); err != nil {
+		t.Fatal(err)
+	}
+	if fixed, err = ReconcileReactions(db); err != nil {
+		t.Fatal(err)
+	}
+	if fixed != 2 {
+		t.Errorf("reconciling fixed %d, want 2", fixed)
+	}
+
+	checkPostVotes(t, posts, liked.Id, 2, 0)
+	checkPostVotes(t, posts, untouched.Id, 0, 1)
+	got, err := comments.GetCommentByID(comment.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Likes != 0 || got.DisLikes != 1 {
+		t.Errorf("comment has %d likes and %d dislikes, want 0 and 1", got.Likes, got.DisLikes)
+	}
+}
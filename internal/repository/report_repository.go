@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"time"
+)
+
+// Report is an interface that defines methods for storing content reports.
+type Report interface {
+	CreateReport(report *models.Report) error
+	HasOpenReport(reporterID, postID, commentID int) (bool, error)
+	GetReportByID(id int) (models.Report, error)
+	GetReportsByStatus(open bool, limit int) ([]models.Report, error)
+	ResolveReports(postID, commentID int, status models.ReportStatus, moderatorID int, resolvedAt time.Time) error
+}
+
+// execer runs statements on the database or in a transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// ReportStorage is a struct that implements the Report interface.
+type ReportStorage struct {
+	db *sql.DB
+}
+
+// NewReportSqlite returns a new ReportStorage instance.
+func NewReportSqlite(db *sql.DB) *ReportStorage {
+	return &ReportStorage{db: db}
+}
+
+// reportColumns selects a report with the names of the people involved and an excerpt of the reported content.
+const reportColumns = `SELECT report.id, report.reporterid, COALESCE(reporter.username, ''), report.postid, report.commentid,
+	report.reason, report.status, report.createdAt, report.moderatorid, COALESCE(moderator.username, ''), report.resolvedAt,
+	CASE WHEN report.commentid = 0 THEN COALESCE(post.title, '') ELSE COALESCE(comment.text, '') END
+	FROM report
+	LEFT JOIN user AS reporter ON reporter.id = report.reporterid
+	LEFT JOIN user AS moderator ON moderator.id = report.moderatorid
+	LEFT JOIN post ON post.id = report.postid
+	LEFT JOIN comment ON comment.id = report.commentid`
+
+// CreateReport stores a new report.
+func (s *ReportStorage) CreateReport(report *models.Report) error {
+	query := `INSERT INTO report (reporterid, postid, commentid, reason, status, createdAt) VALUES ($1, $2, $3, $4, $5, $6);`
+	res, err := s.db.Exec(query, report.ReporterID, report.PostID, report.CommentID, report.Reason, report.Status, report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("storage: create report: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create report: %w", err)
+	}
+	report.ID = int(id)
+	return nil
+}
+
+// HasOpenReport reports whether a user already has an open report on a post or comment.
+func (s *ReportStorage) HasOpenReport(reporterID, postID, commentID int) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM report WHERE reporterid = $1 AND postid = $2 AND commentid = $3 AND status = $4;`
+	if err := s.db.QueryRow(query, reporterID, postID, commentID, models.ReportOpen).Scan(&count); err != nil {
+		return false, fmt.Errorf("storage: has open report: %w", err)
+	}
+	return count > 0, nil
+}
+
+// GetReportByID returns a report by its ID.
+func (s *ReportStorage) GetReportByID(id int) (models.Report, error) {
+	row := s.db.QueryRow(reportColumns+` WHERE report.id = $1;`, id)
+	report, err := scanReport(row)
+	if err != nil {
+		return models.Report{}, fmt.Errorf("storage: get report by id: %w", err)
+	}
+	return report, nil
+}
+
+// GetReportsByStatus returns open reports oldest first, or resolved reports most recently resolved first.
+func (s *ReportStorage) GetReportsByStatus(open bool, limit int) ([]models.Report, error) {
+	query := reportColumns + ` WHERE report.status = $1 ORDER BY report.id LIMIT $2;`
+	if !open {
+		query = reportColumns + ` WHERE report.status != $1 ORDER BY report.resolvedAt DESC LIMIT $2;`
+	}
+
+	rows, err := s.db.Query(query, models.ReportOpen, limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get reports: %w", err)
+	}
+	defer rows.Close()
+
+	var reports []models.Report
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("storage: get reports: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// ResolveReports closes every open report on a post or comment with the action a moderator took.
+func (s *ReportStorage) ResolveReports(postID, commentID int, status models.ReportStatus, moderatorID int, resolvedAt time.Time) error {
+	if err := resolveReports(s.db, postID, commentID, status, moderatorID, resolvedAt); err != nil {
+		return fmt.Errorf("storage: resolve reports: %w", err)
+	}
+	return nil
+}
+
+// resolveReports closes every open report on a post or comment through db, which is the transaction that changes
+// the content when a moderator hides or deletes it.
+func resolveReports(db execer, postID, commentID int, status models.ReportStatus, moderatorID int, resolvedAt time.Time) error {
+	query := `UPDATE report SET status = $1, moderatorid = $2, resolvedAt = $3 WHERE postid = $4 AND commentid = $5 AND status = $6;`
+	_, err := db.Exec(query, status, moderatorID, resolvedAt, postID, commentID, models.ReportOpen)
+	return err
+}
+
+// resolvePostReports closes every open report on a post and on its comments through db.
+func resolvePostReports(db execer, postID int, status models.ReportStatus, moderatorID int, resolvedAt time.Time) error {
+	query := `UPDATE report SET status = $1, moderatorid = $2, resolvedAt = $3 WHERE postid = $4 AND status = $5;`
+	_, err := db.Exec(query, status, moderatorID, resolvedAt, postID, models.ReportOpen)
+	return err
+}
+
+// scanReport reads a row selected with reportColumns.
+func scanReport(row interface{ Scan(dest ...any) error }) (models.Report, error) {
+	var (
+		report     models.Report
+		resolvedAt sql.NullTime
+	)
+	err := row.Scan(&report.ID, &report.ReporterID, &report.Reporter, &report.PostID, &report.CommentID,
+		&report.Reason, &report.Status, &report.CreatedAt, &report.ModeratorID, &report.Moderator, &resolvedAt,
+		&report.Excerpt)
+	if err != nil {
+		return models.Report{}, err
+	}
+	report.ResolvedAt = resolvedAt.Time
+	return report, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"forum/internal/models"
+)
+
+// createTestReport reports a post, or a comment on it when commentID is not 0.
+func createTestReport(t *testing.T, reports *ReportStorage, postID, commentID int) models.Report {
+	t.Helper()
+	report := models.Report{ReporterID: 2, PostID: postID, CommentID: commentID, Reason: "spam", Status: models.ReportOpen, CreatedAt: time.Now()}
+	if err := reports.CreateReport(&report); err != nil {
+		t.Fatalf("create report: %v", err)
+	}
+	return report
+}
+
+// checkReportStatus fails the test unless a report is kept with the given status.
+func checkReportStatus(t *testing.T, reports *ReportStorage, reportID int, status models.ReportStatus) {
+	t.Helper()
+	report, err := reports.GetReportByID(reportID)
+	if err != nil {
+		t.Fatalf("report %d: %v", reportID, err)
+	}
+	if report.Status != status {
+		t.Errorf("report %d is %s, want %s", reportID, report.Status, status)
+	}
+}
+
+// TestModerateClosesReports checks that hiding or deleting content closes its reports with the action and keeps
+// them for the moderation log, and that reports stay open when the content does not change.
+func TestModerateClosesReports(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	comments := NewCommentSqlite(db)
+	reports := NewReportSqlite(db)
+
+	post := createTestPost(t, posts, "Post")
+	deleted := createTestComment(t, db, post.Id, "alice")
+	onComment := createTestReport(t, reports, post.Id, deleted.ID)
+	if err := comments.ModerateComment(deleted.ID, models.ReportDeleted, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	checkReportStatus(t, reports, onComment.ID, models.ReportDeleted)
+	if _, err := comments.GetCommentByID(deleted.ID); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("deleted comment: err = %v, want sql.ErrNoRows", err)
+	}
+
+	comment := createTestComment(t, db, post.Id, "alice")
+	onPost := createTestReport(t, reports, post.Id, 0)
+	onComment = createTestReport(t, reports, post.Id, comment.ID)
+	if err := posts.ModeratePost(post.Id, models.ReportStatus("archived"), 1, time.Now()); err == nil {
+		t.Error("unknown action: err = nil")
+	}
+	checkReportStatus(t, reports, onPost.ID, models.ReportOpen)
+	checkReportStatus(t, reports, onComment.ID, models.ReportOpen)
+
+	if err := posts.ModeratePost(post.Id, models.ReportHidden, 1, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	checkReportStatus(t, reports, onPost.ID, models.ReportHidden)
+	checkReportStatus(t, reports, onComment.ID, models.ReportHidden)
+	hidden, err := posts.GetPostByID(post.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hidden.Hidden {
+		t.Error("post is not hidden")
+	}
+}
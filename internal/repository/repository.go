@@ -2,18 +2,34 @@ package repository
 
 import (
 	"database/sql"
+	"forum/internal/filestore"
 )
 
 type Repository struct {
 	Authorization
+	AccessToken
 	PostItem
+	CategoryItem
+	TagItem
+	AttachmentItem
 	Comment
+	ReactionItem
+	Report
+	SearchIndex
 }
 
-func NewRepository(db *sql.DB) *Repository {
+// NewRepository returns the repositories of the forum, which keep uploaded files in files.
+func NewRepository(db *sql.DB, files filestore.Store) *Repository {
 	return &Repository{
-		Authorization: NewAuthSqlite(db),
-		PostItem:      NewPostSqlite(db),
-		Comment:       NewCommentSqlite(db),
+		Authorization:  NewAuthSqlite(db),
+		AccessToken:    NewAccessTokenSqlite(db),
+		PostItem:       NewPostSqlite(db, files),
+		CategoryItem:   NewCategorySqlite(db),
+		TagItem:        NewTagSqlite(db),
+		AttachmentItem: NewAttachmentSqlite(db, files),
+		Comment:        NewCommentSqlite(db),
+		ReactionItem:   NewReactionSqlite(db),
+		Report:         NewReportSqlite(db),
+		SearchIndex:    NewSearchSqlite(db),
 	}
 }
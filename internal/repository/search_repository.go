@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"strings"
+)
+
+// ErrSearchUnavailable is returned when the SQLite library was built without FTS5,
+// the binary has to be built with the sqlite_fts5 tag for search to work.
+var ErrSearchUnavailable = errors.New("full-text search is not available")
+
+// SearchIndex is an interface that defines the methods for searching posts and comments.
+type SearchIndex interface {
+	Search(match string, request models.SearchRequest, limit, offset int) ([]models.SearchResult, error)
+}
+
+// SearchStorage is a struct that implements the SearchIndex interface.
+type SearchStorage struct {
+	db *sql.DB
+}
+
+// NewSearchSqlite returns a new instance of SearchStorage.
+func NewSearchSqlite(db *sql.DB) *SearchStorage {
+	return &SearchStorage{db: db}
+}
+
+// Markers that snippet() puts around the matching terms, control characters that posts and comments may not contain.
+const (
+	snippetStart = "\x02"
+	snippetEnd   = "\x03"
+)
+
+// Search returns the visible posts and comments that match an FTS5 query, the best matches first.
+// Titles weigh more than the about text, which weighs more than the content of a post.
+func (s *SearchStorage) Search(match string, request models.SearchRequest, limit, offset int) ([]models.SearchResult, error) {
+	postQuery := `SELECT 'post', post.id, 0, post.title, COALESCE(user.username, ''),
+		snippet(post_search, -1, char(2), char(3), '…', 24), bm25(post_search, 10.0, 4.0, 1.0), post.createdAt
+		FROM post_search
+		JOIN post ON post.id = post_search.rowid
+		LEFT JOIN user ON user.id = post.userid
+		WHERE post_search MATCH ? AND post.hidden = 0`
+	commentQuery := `SELECT 'comment', post.id, comment.id, post.title, comment.author,
+		snippet(comment_search, 0, char(2), char(3), '…', 24), bm25(comment_search), comment.createdAt
+		FROM comment_search
+		JOIN comment ON comment.id = comment_search.rowid
+		JOIN post ON post.id = comment.postid
+		WHERE comment_search MATCH ? AND comment.hidden = 0 AND comment.deleted = 0 AND post.hidden = 0`
+	postArgs := []any{match}
+	commentArgs := []any{match}
+
+	if request.Category != "" {
+		filter := ` AND post.id IN (SELECT post_category.postId FROM post_category
+			JOIN category ON category.id = post_category.categoryid WHERE category.slug = ?)`
+		postQuery += filter
+		commentQuery += filter
+		postArgs = append(postArgs, request.Category)
+		commentArgs = append(commentArgs, request.Category)
+	}
+	if request.Author != "" {
+		postQuery += ` AND user.username = ?`
+		commentQuery += ` AND comment.author = ?`
+		postArgs = append(postArgs, request.Author)
+		commentArgs = append(commentArgs, request.Author)
+	}
+
+	query := postQuery + " UNION ALL " + commentQuery + " ORDER BY 7, 2 DESC, 3 LIMIT ? OFFSET ?"
+	args := append(append(postArgs, commentArgs...), limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") || strings.Contains(err.Error(), "no such module") {
+			return nil, fmt.Errorf("storage: search: %w", ErrSearchUnavailable)
+		}
+		return nil, fmt.Errorf("storage: search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []models.SearchResult
+	for rows.Next() {
+		var (
+			result  models.SearchResult
+			snippet string
+			rank    float64
+		)
+		if err = rows.Scan(&result.Kind, &result.PostID, &result.CommentID, &result.Title, &result.Author, &snippet, &rank, &result.CreatedAt); err != nil {
+			return nil, fmt.Errorf("storage: search: %w", err)
+		}
+		result.Snippet = snippetParts(snippet)
+		results = append(results, result)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage: search: %w", err)
+	}
+	return results, nil
+}
+
+// snippetParts splits a snippet at the markers around its matching terms.
+func snippetParts(snippet string) []models.SnippetPart {
+	var parts []models.SnippetPart
+	for snippet != "" {
+		start := strings.Index(snippet, snippetStart)
+		if start < 0 {
+			parts = append(parts, models.SnippetPart{Text: snippet})
+			break
+		}
+		if start > 0 {
+			parts = append(parts, models.SnippetPart{Text: snippet[:start]})
+		}
+		snippet = snippet[start+len(snippetStart):]
+
+		end := strings.Index(snippet, snippetEnd)
+		if end < 0 {
+			end = len(snippet)
+		}
+		parts = append(parts, models.SnippetPart{Text: snippet[:end], Match: true})
+		snippet = strings.TrimPrefix(snippet[end:], snippetEnd)
+	}
+	return parts
+}
@@ -0,0 +1,120 @@
+//go:build sqlite_fts5
+
+package repository
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"forum/internal/models"
+)
+
+// createSearchComment creates a comment with the given text, a reply when parentID is not 0.
+func createSearchComment(t *testing.T, comments *CommentStorage, postID, parentID int, text string) models.Comment {
+	t.Helper()
+	now := models.Timestamp{Time: time.Now()}
+	comment := models.Comment{PostID: postID, ParentID: parentID, Author: "alice", Text: text, CreatedAt: now, UpdatedAt: now}
+	if err := comments.CreateComment(&comment); err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	return comment
+}
+
+// searchHits runs a search and names what it found, "post 1" or "comment 2", in a stable order.
+func searchHits(t *testing.T, storage *SearchStorage, match string) []string {
+	t.Helper()
+	results, err := storage.Search(match, models.SearchRequest{}, 100, 0)
+	if err != nil {
+		t.Fatalf("search %s: %v", match, err)
+	}
+	hits := []string{}
+	for _, result := range results {
+		if result.Kind == "comment" {
+			hits = append(hits, fmt.Sprintf("comment %d", result.CommentID))
+		} else {
+			hits = append(hits, fmt.Sprintf("post %d", result.PostID))
+		}
+	}
+	sort.Strings(hits)
+	return hits
+}
+
+// TestSearchHiddenAndDeleted checks that hidden and deleted posts and comments, and the comments of hidden posts,
+// are never found.
+func TestSearchHiddenAndDeleted(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	comments := NewCommentSqlite(db)
+
+	post := createTestPost(t, posts, "Visible zebra")
+	comment := createSearchComment(t, comments, post.Id, 0, "zebra on a visible post")
+
+	hiddenComment := createSearchComment(t, comments, post.Id, 0, "hidden zebra")
+	if err := comments.SetCommentHidden(hiddenComment.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	deletedComment := createSearchComment(t, comments, post.Id, 0, "deleted zebra")
+	placeholder := createSearchComment(t, comments, post.Id, 0, "zebra with a reply")
+	createSearchComment(t, comments, post.Id, placeholder.ID, "a reply")
+	for _, id := range []int{deletedComment.ID, placeholder.ID} {
+		if err := comments.DeleteComment(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hiddenPost := createTestPost(t, posts, "Hidden zebra")
+	createSearchComment(t, comments, hiddenPost.Id, 0, "zebra on a hidden post")
+	if err := posts.SetPostHidden(hiddenPost.Id, true); err != nil {
+		t.Fatal(err)
+	}
+	deletedPost := createTestPost(t, posts, "Deleted zebra")
+	createSearchComment(t, comments, deletedPost.Id, 0, "zebra on a deleted post")
+	if err := posts.DeletePost(deletedPost.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{fmt.Sprintf("comment %d", comment.ID), fmt.Sprintf("post %d", post.Id)}
+	if got := searchHits(t, NewSearchSqlite(db), `"zebra"`); !reflect.DeepEqual(got, want) {
+		t.Errorf("found %v, want %v", got, want)
+	}
+}
+
+// TestSearchQuotedOperators checks that FTS5 operators and syntax quoted the way the search service quotes every
+// term are looked for as plain words, and that a quoted prefix still matches the words it starts.
+func TestSearchQuotedOperators(t *testing.T) {
+	db := testDB(t)
+	posts := NewPostSqlite(db, nil)
+	titles := []string{"Cats and dogs", "Either or neither", "Do not touch", "Prefixes everywhere", "Near the end", "Key: value pairs", "Open (parenthesis"}
+	ids := make(map[string]string)
+	for _, title := range titles {
+		ids[title] = fmt.Sprintf("post %d", createTestPost(t, posts, title).Id)
+	}
+
+	tests := []struct {
+		match string
+		want  []string
+	}{
+		{`"AND"`, []string{ids["Cats and dogs"]}},
+		{`"OR"`, []string{ids["Either or neither"]}},
+		{`"NOT" "touch"`, []string{ids["Do not touch"]}},
+		{`"NEAR(the"`, []string{ids["Near the end"]}},
+		{`"pre"*`, []string{ids["Prefixes everywhere"]}},
+		{`"key:value"`, []string{ids["Key: value pairs"]}},
+		{`"(parenthesis"`, []string{ids["Open (parenthesis"]}},
+		{`"-dogs"`, []string{ids["Cats and dogs"]}},
+		{`"^the end"`, []string{ids["Near the end"]}},
+		{`"the*"`, []string{ids["Near the end"]}},
+		{`"zebra"`, []string{}},
+	}
+	storage := NewSearchSqlite(db)
+	for _, tt := range tests {
+		want := append([]string{}, tt.want...)
+		sort.Strings(want)
+		if got := searchHits(t, storage, tt.match); !reflect.DeepEqual(got, want) {
+			t.Errorf("search %s found %v, want %v", tt.match, got, want)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"strings"
+)
+
+// TagItem is an interface that defines the methods for reading tags.
+// Tags are put on posts through PostItem.
+type TagItem interface {
+	ListTags(prefix string, limit int) ([]models.Tag, error)
+}
+
+// TagStorage is a struct that implements the TagItem interface.
+type TagStorage struct {
+	db *sql.DB
+}
+
+// NewTagSqlite returns a new instance of TagStorage.
+func NewTagSqlite(db *sql.DB) *TagStorage {
+	return &TagStorage{db: db}
+}
+
+// tagPrefixEscaper escapes the LIKE wildcards in a tag prefix.
+var tagPrefixEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// ListTags returns the tags that start with prefix and are carried by at least one visible post,
+// the most used first, with at most limit of them.
+func (s *TagStorage) ListTags(prefix string, limit int) ([]models.Tag, error) {
+	query := `SELECT tag.name, COUNT(*) AS uses FROM tag
+		JOIN post_tag ON post_tag.tagid = tag.id
+		JOIN post ON post.id = post_tag.postid AND post.hidden = 0
+		WHERE tag.name LIKE $1 ESCAPE '\'
+		GROUP BY tag.id ORDER BY uses DESC, tag.name LIMIT $2;`
+	rows, err := s.db.Query(query, tagPrefixEscaper.Replace(prefix)+"%", limit)
+	if err != nil {
+		return nil, fmt.Errorf("storage: list tags: %w", err)
+	}
+	defer rows.Close()
+
+	var tags []models.Tag
+	for rows.Next() {
+		var tag models.Tag
+		if err = rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, fmt.Errorf("storage: list tags: %w", err)
+		}
+		tags = append(tags, tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("storage: list tags: %w", err)
+	}
+	return tags, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"forum/internal/models"
+	"strings"
+	"time"
+)
+
+// AccessToken is an interface that defines methods for storing personal access tokens.
+type AccessToken interface {
+	CreateAccessToken(token *models.AccessToken) error
+	GetAccessTokensByUserID(userID int) ([]models.AccessToken, error)
+	GetUserByAccessTokenHash(hash string) (models.User, models.AccessToken, error)
+	UpdateAccessTokenLastUsed(tokenID int, lastUsedAt time.Time) error
+	DeleteAccessToken(userID, tokenID int) (bool, error)
+}
+
+// AccessTokenStorage is a struct that implements the AccessToken interface.
+type AccessTokenStorage struct {
+	db *sql.DB
+}
+
+// NewAccessTokenSqlite returns a new AccessTokenStorage instance.
+func NewAccessTokenSqlite(db *sql.DB) *AccessTokenStorage {
+	return &AccessTokenStorage{db: db}
+}
+
+// CreateAccessToken stores a new access token. Only the hash of the token is saved.
+func (s *AccessTokenStorage) CreateAccessToken(token *models.AccessToken) error {
+	query := `INSERT INTO access_token (userid, name, tokenHash, scopes, createdAt, lastUsedAt) VALUES ($1, $2, $3, $4, $5, $6);`
+	res, err := s.db.Exec(query, token.UserID, token.Name, token.TokenHash, strings.Join(token.Scopes, ","), token.CreatedAt, token.LastUsedAt)
+	if err != nil {
+		return fmt.Errorf("storage: create access token: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("storage: create access token: %w", err)
+	}
+	token.ID = int(id)
+	return nil
+}
+
+// GetAccessTokensByUserID returns the access tokens of a user, newest first.
+func (s *AccessTokenStorage) GetAccessTokensByUserID(userID int) ([]models.AccessToken, error) {
+	query := `SELECT id, userid, name, scopes, createdAt, lastUsedAt FROM access_token WHERE userid = $1 ORDER BY id DESC;`
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("storage: get access tokens by user id: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []models.AccessToken
+	for rows.Next() {
+		var (
+			token  models.AccessToken
+			scopes string
+		)
+		if err := rows.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.CreatedAt, &token.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("storage: get access tokens by user id: %w", err)
+		}
+		token.Scopes = splitScopes(scopes)
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetUserByAccessTokenHash returns the owner of an access token together with the token itself.
+func (s *AccessTokenStorage) GetUserByAccessTokenHash(hash string) (models.User, models.AccessToken, error) {
+	query := `SELECT user.id, user.email, user.username, user.password, user.role, user.createdAt, user.updatedAt,
+	access_token.id, access_token.name, access_token.scopes
+	FROM access_token INNER JOIN user ON user.id = access_token.userid WHERE access_token.tokenHash = $1;`
+
+	var (
+		user   models.User
+		token  models.AccessToken
+		scopes string
+	)
+	err := s.db.QueryRow(query, hash).Scan(&user.ID, &user.Email, &user.Username, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt,
+		&token.ID, &token.Name, &scopes)
+	if err != nil {
+		return models.User{}, models.AccessToken{}, fmt.Errorf("storage: get user by access token: %w", err)
+	}
+	token.UserID = user.ID
+	token.Scopes = splitScopes(scopes)
+	return user, token, nil
+}
+
+// UpdateAccessTokenLastUsed records the last time an access token was used.
+func (s *AccessTokenStorage) UpdateAccessTokenLastUsed(tokenID int, lastUsedAt time.Time) error {
+	query := `UPDATE access_token SET lastUsedAt = $1 WHERE id = $2;`
+	if _, err := s.db.Exec(query, lastUsedAt, tokenID); err != nil {
+		return fmt.Errorf("storage: update access token last used: %w", err)
+	}
+	return nil
+}
+
+// DeleteAccessToken removes an access token of a user and reports whether it existed.
+func (s *AccessTokenStorage) DeleteAccessToken(userID, tokenID int) (bool, error) {
+	query := `DELETE FROM access_token WHERE id = $1 AND userid = $2;`
+	res, err := s.db.Exec(query, tokenID, userID)
+	if err != nil {
+		return false, fmt.Errorf("storage: delete access token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("storage: delete access token: %w", err)
+	}
+	return n > 0, nil
+}
+
+func splitScopes(scopes string) []string {
+	if scopes == "" {
+		return nil
+	}
+	return strings.Split(scopes, ",")
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	// A custom error that is returned when an uploaded file cannot be attached to a post.
+	ErrInvalidAttachment = errors.New("invalid attachment")
+	// A custom error that is returned when an attachment does not exist or belongs to a hidden post.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+const (
+	// MaxAttachmentSize is the size in bytes of the largest file that can be attached to a post.
+	MaxAttachmentSize = 5 << 20
+	// MaxPostAttachments is how many files can be attached to a post.
+	MaxPostAttachments = 4
+
+	// maxAttachmentPixels bounds the size of images, which are decoded in full to make their thumbnails.
+	maxAttachmentPixels = 4096 * 4096
+	maxAttachmentName   = 100
+	// thumbnailSize is the width and height that thumbnails fit in.
+	thumbnailSize = 320
+)
+
+// attachmentTypes maps the content types that can be attached, as sniffed from the files, to the extension of the files.
+var attachmentTypes = map[string]string{
+	"image/png":       ".png",
+	"image/jpeg":      ".jpg",
+	"image/gif":       ".gif",
+	"application/pdf": ".pdf",
+}
+
+// An interface that defines methods for reading the files attached to posts. It is implemented by the AttachmentService struct.
+type AttachmentItem interface {
+	GetAttachment(actor models.User, id int) (models.Attachment, error)
+	OpenAttachment(attachment models.Attachment, thumbnail bool) (io.ReadSeekCloser, error)
+}
+
+type AttachmentService struct {
+	repo  repository.AttachmentItem
+	posts repository.PostItem
+}
+
+// NewAttachmentService returns a new instance of AttachmentService.
+func NewAttachmentService(repo repository.AttachmentItem, posts repository.PostItem) *AttachmentService {
+	return &AttachmentService{repo: repo, posts: posts}
+}
+
+// GetAttachment returns an attachment by id. The attachments of hidden posts are only found for moderators.
+func (a *AttachmentService) GetAttachment(actor models.User, id int) (models.Attachment, error) {
+	attachment, err := a.repo.GetAttachmentByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Attachment{}, ErrAttachmentNotFound
+		}
+		return models.Attachment{}, fmt.Errorf("service: get attachment: %w", err)
+	}
+
+	post, err := a.posts.GetPostByID(attachment.PostID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Attachment{}, ErrAttachmentNotFound
+		}
+		return models.Attachment{}, fmt.Errorf("service: get attachment: %w", err)
+	}
+	if post.Hidden && !actor.Role.AtLeast(models.RoleModerator) {
+		return models.Attachment{}, ErrAttachmentNotFound
+	}
+	return attachment, nil
+}
+
+// OpenAttachment opens the file of an attachment, or its thumbnail, which only images have.
+func (a *AttachmentService) OpenAttachment(attachment models.Attachment, thumbnail bool) (io.ReadSeekCloser, error) {
+	key := attachment.FileKey
+	if thumbnail {
+		key = attachment.ThumbnailKey
+	}
+	if key == "" {
+		return nil, ErrAttachmentNotFound
+	}
+
+	file, err := a.repo.OpenAttachmentFile(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("service: open attachment: %w", err)
+	}
+	return file, nil
+}
+
+// preparedAttachment is an uploaded file that passed the checks, together with its thumbnail, ready to be stored.
+type preparedAttachment struct {
+	attachment   models.Attachment
+	file         []byte
+	fileExt      string
+	thumbnail    []byte
+	thumbnailExt string
+}
+
+// prepareAttachments checks the files uploaded with a new post and makes the thumbnails of the images.
+func prepareAttachments(uploads []models.Upload) ([]preparedAttachment, error) {
+	if len(uploads) > MaxPostAttachments {
+		return nil, fmt.Errorf("more than %d files: %w", MaxPostAttachments, ErrInvalidAttachment)
+	}
+
+	prepared := make([]preparedAttachment, 0, len(uploads))
+	for _, upload := range uploads {
+		attachment, err := prepareAttachment(upload)
+		if err != nil {
+			return nil, err
+		}
+		prepared = append(prepared, attachment)
+	}
+	return prepared, nil
+}
+
+// prepareAttachment checks an uploaded file. Its type is sniffed from its content rather than taken from its name,
+// and images must decode, for their thumbnails are made from them.
+func prepareAttachment(upload models.Upload) (preparedAttachment, error) {
+	if len(upload.Data) == 0 {
+		return preparedAttachment{}, fmt.Errorf("file %q is empty: %w", upload.Name, ErrInvalidAttachment)
+	}
+	if len(upload.Data) > MaxAttachmentSize {
+		return preparedAttachment{}, fmt.Errorf("file %q is larger than %d bytes: %w", upload.Name, MaxAttachmentSize, ErrInvalidAttachment)
+	}
+
+	contentType := http.DetectContentType(upload.Data)
+	ext, ok := attachmentTypes[contentType]
+	if !ok {
+		return preparedAttachment{}, fmt.Errorf("file %q has unsupported type %s: %w", upload.Name, contentType, ErrInvalidAttachment)
+	}
+
+	prepared := preparedAttachment{
+		attachment: models.Attachment{
+			Name:        attachmentName(upload.Name, ext),
+			ContentType: contentType,
+			Size:        int64(len(upload.Data)),
+		},
+		file:    upload.Data,
+		fileExt: ext,
+	}
+	if !strings.HasPrefix(contentType, "image/") {
+		return prepared, nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(upload.Data))
+	if err != nil {
+		return preparedAttachment{}, fmt.Errorf("image %q: %v: %w", upload.Name, err, ErrInvalidAttachment)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxAttachmentPixels {
+		return preparedAttachment{}, fmt.Errorf("image %q is larger than %d pixels: %w", upload.Name, maxAttachmentPixels, ErrInvalidAttachment)
+	}
+	img, _, err := image.Decode(bytes.NewReader(upload.Data))
+	if err != nil {
+		return preparedAttachment{}, fmt.Errorf("image %q: %v: %w", upload.Name, err, ErrInvalidAttachment)
+	}
+	prepared.attachment.Width = config.Width
+	prepared.attachment.Height = config.Height
+
+	// Photos keep to JPEG, other images go to PNG for their transparency.
+	var buf bytes.Buffer
+	small := thumbnail(img, thumbnailSize)
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, small, &jpeg.Options{Quality: 85})
+		prepared.thumbnailExt = ".jpg"
+	} else {
+		err = png.Encode(&buf, small)
+		prepared.thumbnailExt = ".png"
+	}
+	if err != nil {
+		return preparedAttachment{}, fmt.Errorf("thumbnail of %q: %w", upload.Name, err)
+	}
+	prepared.thumbnail = buf.Bytes()
+	return prepared, nil
+}
+
+// attachmentName cleans the name of an uploaded file for showing and downloading it: directories are dropped,
+// it is cut to maxAttachmentName characters and its extension is replaced by ext, the one of its sniffed type.
+func attachmentName(name, ext string) string {
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+	name, ok := normalizeText(strings.TrimSpace(name), "")
+	if !ok {
+		name = ""
+	}
+	name = strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+
+	limit := maxAttachmentName - len(ext)
+	if utf8.RuneCountInString(name) > limit {
+		name = string([]rune(name)[:limit])
+	}
+	if name == "" {
+		name = "attachment"
+	}
+	return name + ext
+}
+
+// thumbnail scales an image down to fit in a square of size pixels, every pixel of the thumbnail is the average
+// of the pixels that it covers. Images that fit already keep their size.
+func thumbnail(img image.Image, size int) *image.NRGBA {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	thumbWidth, thumbHeight := width, height
+	if width > size || height > size {
+		if width >= height {
+			thumbWidth, thumbHeight = size, height*size/width
+		} else {
+			thumbWidth, thumbHeight = width*size/height, size
+		}
+		if thumbWidth < 1 {
+			thumbWidth = 1
+		}
+		if thumbHeight < 1 {
+			thumbHeight = 1
+		}
+	}
+
+	thumb := image.NewNRGBA(image.Rect(0, 0, thumbWidth, thumbHeight))
+	for y := 0; y < thumbHeight; y++ {
+		y0, y1 := bounds.Min.Y+y*height/thumbHeight, bounds.Min.Y+(y+1)*height/thumbHeight
+		for x := 0; x < thumbWidth; x++ {
+			x0, x1 := bounds.Min.X+x*width/thumbWidth, bounds.Min.X+(x+1)*width/thumbWidth
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			thumb.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+	return thumb
+}
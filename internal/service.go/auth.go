@@ -5,29 +5,41 @@ import (
 	"fmt"
 	"forum/internal/models"
 	"forum/internal/repository"
+	"forum/internal/text"
 	"net/http"
 	"net/mail"
+	"strings"
 	"time"
+	"unicode"
 
 	uuid "github.com/satori/go.uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrInvalidEmail    = errors.New("invalid email")
-	ErrInvalidUsername = errors.New("invalid username")
-	ErrInvalidPassword = errors.New("invalid password")
-	ErrUserNotFound    = errors.New("user not found")
-	ErrUserExist       = errors.New("user exist")
+	ErrInvalidEmail       = errors.New("invalid email")
+	ErrInvalidUsername    = errors.New("invalid username")
+	ErrInvalidPassword    = errors.New("invalid password")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrUserExist          = errors.New("user exist")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrInvalidRole        = errors.New("invalid role")
+	ErrUsernameConfusable = errors.New("username looks like an existing one")
 )
 
 // An interface that defines methods for managing user authentication and session management.
 type Authorization interface {
 	CreateUser(user *models.User) error
-	GenerateSessionToken(email, password string) (string, time.Time, error)
+	GenerateSessionToken(email, password, userAgent, ip string) (string, time.Time, error)
 	GetSessionToken(token string) (models.User, error)
 	GetSessionTokenFromRequest(r *http.Request) models.User
+	GetUserSessions(userID int) ([]models.Session, error)
 	DeleteSessionToken(token string) error
+	RevokeSession(userID, sessionID int) error
+	DeleteUserSessions(userID int) error
+	GetUsers() ([]models.User, error)
+	SetUserRole(actor models.User, userID int, role models.Role) error
+	SetUserRoleByEmail(email string, role models.Role) error
 }
 
 // struct that implements the Authorization interface.
@@ -56,16 +68,28 @@ func (s *AuthService) CreateUser(user *models.User) error {
 		return ErrUserExist
 	}
 
+	// Names that only differ by case, accents or letters of other scripts that look the same would let
+	// one user pass for another.
+	if _, err = s.repo.GetUserBySkeleton(text.Skeleton(user.Username)); err == nil {
+		return ErrUsernameConfusable
+	}
+
 	user.Password, err = generateHashPassword(user.Password)
 	if err != nil {
 		return fmt.Errorf("service: create user: %w", err)
 	}
 
-	return s.repo.CreateUser(user)
+	user.Role = models.RoleUser
+	user.CreatedAt = models.Timestamp{Time: time.Now()}
+	user.UpdatedAt = user.CreatedAt
+
+	// The first account of a fresh forum administers it.
+	return s.repo.CreateUser(user, models.RoleAdministrator)
 }
 
 // GenerateSessionToken generates a new session token for the user.
-func (s *AuthService) GenerateSessionToken(email, password string) (string, time.Time, error) {
+// Every call opens a separate session, so signing in on one device keeps the others signed in.
+func (s *AuthService) GenerateSessionToken(email, password, userAgent, ip string) (string, time.Time, error) {
 	user, err := s.repo.GetUserByEmail(email)
 	if err != nil {
 		return "", time.Time{}, err
@@ -77,21 +101,41 @@ func (s *AuthService) GenerateSessionToken(email, password string) (string, time
 		return "", time.Time{}, passwordComparasionError
 	}
 
-	token := uuid.NewV4().String()
-	expiresAt := time.Now().Add(time.Hour * 12)
+	now := time.Now()
+	if err = s.repo.DeleteExpiredSessions(now); err != nil {
+		return "", time.Time{}, fmt.Errorf("service: generate session token: %w", err)
+	}
 
-	err = s.repo.AddSessionToken(email, token, expiresAt)
+	session := &models.Session{
+		UserID:     user.ID,
+		Token:      uuid.NewV4().String(),
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(time.Hour * 12),
+	}
+
+	if err = s.repo.AddSessionToken(session); err != nil {
+		return "", time.Time{}, fmt.Errorf("service: generate session token: %w", err)
+	}
 
-	return token, expiresAt, nil
+	return session.Token, session.ExpiresAt, nil
 }
 
-// GetSessionToken returns a user by session token.
+// GetSessionToken returns a user by session token and marks the session as seen.
 func (s *AuthService) GetSessionToken(token string) (models.User, error) {
 	user, err := s.repo.GetSessionToken(token)
 	if err != nil {
 		return models.User{}, err
 	}
 
+	if user.ExpiresAt.After(time.Now()) {
+		if err = s.repo.UpdateSessionLastSeen(token, time.Now()); err != nil {
+			return models.User{}, fmt.Errorf("service: get session token: %w", err)
+		}
+	}
+
 	return user, nil
 }
 
@@ -110,6 +154,15 @@ func (s *AuthService) GetSessionTokenFromRequest(r *http.Request) models.User {
 	return user
 }
 
+// GetUserSessions returns the active sessions of a user.
+func (s *AuthService) GetUserSessions(userID int) ([]models.Session, error) {
+	sessions, err := s.repo.GetSessionsByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get user sessions: %w", err)
+	}
+	return sessions, nil
+}
+
 // DeleteSessionToken deletes a session token from the database.
 func (s *AuthService) DeleteSessionToken(token string) error {
 	err := s.repo.DeleteSessionToken(token)
@@ -119,13 +172,73 @@ func (s *AuthService) DeleteSessionToken(token string) error {
 	return nil
 }
 
+// RevokeSession signs the user out of a single session. Only sessions owned by the user can be revoked.
+func (s *AuthService) RevokeSession(userID, sessionID int) error {
+	ok, err := s.repo.DeleteUserSession(userID, sessionID)
+	if err != nil {
+		return fmt.Errorf("service: revoke session: %w", err)
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions signs the user out on every device.
+func (s *AuthService) DeleteUserSessions(userID int) error {
+	if err := s.repo.DeleteUserSessions(userID); err != nil {
+		return fmt.Errorf("service: delete user sessions: %w", err)
+	}
+	return nil
+}
+
+// GetUsers returns every registered user.
+func (s *AuthService) GetUsers() ([]models.User, error) {
+	users, err := s.repo.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("service: get users: %w", err)
+	}
+	return users, nil
+}
+
+// SetUserRole changes the role of a user. Only administrators can change roles,
+// and they cannot change their own so that the forum always keeps an administrator.
+func (s *AuthService) SetUserRole(actor models.User, userID int, role models.Role) error {
+	if !actor.Role.AtLeast(models.RoleAdministrator) || actor.ID == userID {
+		return ErrForbidden
+	}
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+	if err := s.repo.UpdateUserRole(userID, role, time.Now()); err != nil {
+		return fmt.Errorf("service: set user role: %w", err)
+	}
+	return nil
+}
+
+// SetUserRoleByEmail changes the role of the user with the given email.
+// It is meant for bootstrapping from the command line and skips the permission checks of SetUserRole.
+func (s *AuthService) SetUserRoleByEmail(email string, role models.Role) error {
+	if !role.IsValid() {
+		return ErrInvalidRole
+	}
+	user, err := s.repo.GetUserByEmail(email)
+	if err != nil {
+		return fmt.Errorf("service: set user role: %w: %v", ErrUserNotFound, err)
+	}
+	if err = s.repo.UpdateUserRole(user.ID, role, time.Now()); err != nil {
+		return fmt.Errorf("service: set user role: %w", err)
+	}
+	return nil
+}
+
 func generateHashPassword(password string) (string, error) {
 	hashedPassword, hashingError := bcrypt.GenerateFromPassword([]byte(password), 10)
 
 	return string(hashedPassword), hashingError
 }
 
-// isValidUser checks if the user is valid.
+// isValidUser checks if the user is valid, and normalizes the username.
 func isValidUser(user *models.User) error {
 	_, err := mail.ParseAddress(user.Email)
 	if err != nil {
@@ -138,16 +251,13 @@ func isValidUser(user *models.User) error {
 		}
 	}
 
-	for _, char := range user.Username {
-		if char < 32 || char > 126 {
-			return ErrInvalidUsername
-		}
-	}
-
-	if len(user.Username) < 2 || len(user.Username) >= 20 {
-		return ErrInvalidUsername
+	username, err := normalizeUsername(user.Username)
+	if err != nil {
+		return err
 	}
+	user.Username = username
 
+	// Passwords stay within printable ASCII, so that 20 characters always fit in the 72 bytes that bcrypt hashes.
 	for _, char := range user.Password {
 		if char < 33 || char > 126 {
 			return ErrInvalidUsername
@@ -160,3 +270,22 @@ func isValidUser(user *models.User) error {
 
 	return nil
 }
+
+// normalizeUsername trims a username and puts it in NFC, and checks that it holds from 2 to 19 characters.
+// Usernames may be written in any script and hold symbols, but no control, format or private use characters,
+// no spaces other than the plain one and no combining mark at the start.
+func normalizeUsername(username string) (string, error) {
+	username, ok := normalizeText(strings.TrimSpace(username), "")
+	if !ok || !isValidLength(username, 2, 19) {
+		return "", ErrInvalidUsername
+	}
+	for i, char := range username {
+		switch {
+		case unicode.IsSpace(char) && char != ' ',
+			!unicode.In(char, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Zs),
+			i == 0 && unicode.IsMark(char):
+			return "", ErrInvalidUsername
+		}
+	}
+	return username, nil
+}
@@ -0,0 +1,33 @@
+package service
+
+import (
+	"errors"
+	"forum/internal/models"
+)
+
+// ErrForbidden is returned when a user tries to change content that they are not allowed to change.
+var ErrForbidden = errors.New("forbidden")
+
+// authorizePost checks that actor may edit or delete post.
+// Moderators may change any post, other users only their own.
+func authorizePost(actor models.User, post models.Post) error {
+	if actor.ID == 0 {
+		return ErrForbidden
+	}
+	if actor.ID != post.UserID && !actor.Role.AtLeast(models.RoleModerator) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// authorizeComment checks that actor may edit or delete comment.
+// Moderators may change any comment, other users only their own.
+func authorizeComment(actor models.User, comment models.Comment) error {
+	if actor.Username == "" {
+		return ErrForbidden
+	}
+	if actor.Username != comment.Author && !actor.Role.AtLeast(models.RoleModerator) {
+		return ErrForbidden
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	// A custom error that is returned when a category fails to meet validation criteria.
+	ErrInvalidCategory = errors.New("invalid category")
+	// A custom error that is returned when a category does not exist.
+	ErrCategoryNotFound = errors.New("category not found")
+	// A custom error that is returned when another category already has the name or slug.
+	ErrCategoryExists = errors.New("category already exists")
+)
+
+const (
+	maxCategoryName        = 32
+	maxCategorySlug        = 32
+	maxCategoryDescription = 200
+)
+
+// An interface that defines methods for managing categories. It is implemented by the CategoryService struct.
+type CategoryItem interface {
+	GetCategories() ([]models.Category, error)
+	GetAllCategories() ([]models.Category, error)
+	GetCategoryBySlug(slug string) (models.Category, error)
+	CreateCategory(actor models.User, category models.Category) (models.Category, error)
+	UpdateCategory(actor models.User, id int, name, description string, position int) error
+	SetCategoryArchived(actor models.User, id int, archived bool) error
+	SubscribeCategory(actor models.User, id int, subscribed bool) (models.Category, error)
+	GetSubscribedCategories(userID int) ([]models.Category, error)
+}
+
+type CategoryService struct {
+	repo repository.CategoryItem
+}
+
+// NewCategoryService returns a new instance of CategoryService.
+func NewCategoryService(repo repository.CategoryItem) *CategoryService {
+	return &CategoryService{repo: repo}
+}
+
+// GetCategories returns the categories that new posts can be filed under, in navigation order.
+func (c *CategoryService) GetCategories() ([]models.Category, error) {
+	categories, err := c.repo.ListCategories(false)
+	if err != nil {
+		return nil, fmt.Errorf("service: get categories: %w", err)
+	}
+	return categories, nil
+}
+
+// GetAllCategories returns every category including the archived ones, in navigation order.
+func (c *CategoryService) GetAllCategories() ([]models.Category, error) {
+	categories, err := c.repo.ListCategories(true)
+	if err != nil {
+		return nil, fmt.Errorf("service: get all categories: %w", err)
+	}
+	return categories, nil
+}
+
+// GetCategoryBySlug returns a category by its slug, slugs are matched regardless of case.
+func (c *CategoryService) GetCategoryBySlug(slug string) (models.Category, error) {
+	category, err := c.repo.GetCategoryBySlug(strings.ToLower(strings.TrimSpace(slug)))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Category{}, ErrCategoryNotFound
+		}
+		return models.Category{}, fmt.Errorf("service: get category by slug: %w", err)
+	}
+	return category, nil
+}
+
+// CreateCategory adds a category at the end of the navigation, an empty slug is derived from the name.
+// Only administrators may manage categories.
+func (c *CategoryService) CreateCategory(actor models.User, category models.Category) (models.Category, error) {
+	if !actor.Role.AtLeast(models.RoleAdministrator) {
+		return models.Category{}, ErrForbidden
+	}
+
+	category.Slug = strings.ToLower(strings.TrimSpace(category.Slug))
+	if category.Slug == "" {
+		category.Slug = models.CategorySlug(category.Name)
+	}
+	if err := isValidCategory(&category); err != nil {
+		return models.Category{}, err
+	}
+
+	categories, err := c.repo.ListCategories(true)
+	if err != nil {
+		return models.Category{}, fmt.Errorf("service: create category: %w", err)
+	}
+	category.Position = 1
+	for _, other := range categories {
+		if other.Slug == category.Slug || strings.EqualFold(other.Name, category.Name) {
+			return models.Category{}, ErrCategoryExists
+		}
+		if other.Position >= category.Position {
+			category.Position = other.Position + 1
+		}
+	}
+	category.Archived = false
+
+	if err = c.repo.CreateCategory(&category); err != nil {
+		return models.Category{}, fmt.Errorf("service: create category: %w", err)
+	}
+	return category, nil
+}
+
+// UpdateCategory renames a category and changes its description and position, its slug stays the same.
+// Only administrators may manage categories.
+func (c *CategoryService) UpdateCategory(actor models.User, id int, name, description string, position int) error {
+	if !actor.Role.AtLeast(models.RoleAdministrator) {
+		return ErrForbidden
+	}
+
+	category, err := c.getCategory(id)
+	if err != nil {
+		return fmt.Errorf("service: update category: %w", err)
+	}
+
+	category.Name = name
+	category.Description = description
+	category.Position = position
+	if err = isValidCategory(&category); err != nil {
+		return err
+	}
+
+	categories, err := c.repo.ListCategories(true)
+	if err != nil {
+		return fmt.Errorf("service: update category: %w", err)
+	}
+	for _, other := range categories {
+		if other.ID != id && strings.EqualFold(other.Name, category.Name) {
+			return ErrCategoryExists
+		}
+	}
+
+	return c.repo.UpdateCategory(category)
+}
+
+// SetCategoryArchived archives a category or brings it back.
+// Only administrators may manage categories.
+func (c *CategoryService) SetCategoryArchived(actor models.User, id int, archived bool) error {
+	if !actor.Role.AtLeast(models.RoleAdministrator) {
+		return ErrForbidden
+	}
+
+	if _, err := c.getCategory(id); err != nil {
+		return fmt.Errorf("service: set category archived: %w", err)
+	}
+
+	return c.repo.SetCategoryArchived(id, archived)
+}
+
+// SubscribeCategory subscribes a signed in user to a category, or unsubscribes them when subscribed is false.
+func (c *CategoryService) SubscribeCategory(actor models.User, id int, subscribed bool) (models.Category, error) {
+	if actor.ID == 0 {
+		return models.Category{}, ErrForbidden
+	}
+
+	category, err := c.getCategory(id)
+	if err != nil {
+		return models.Category{}, fmt.Errorf("service: subscribe category: %w", err)
+	}
+
+	if err = c.repo.SetCategorySubscription(actor.ID, id, subscribed, time.Now()); err != nil {
+		return models.Category{}, fmt.Errorf("service: subscribe category: %w", err)
+	}
+	return category, nil
+}
+
+// GetSubscribedCategories returns the categories that a user subscribed to, in navigation order.
+func (c *CategoryService) GetSubscribedCategories(userID int) ([]models.Category, error) {
+	categories, err := c.repo.GetSubscribedCategories(userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get subscribed categories: %w", err)
+	}
+	return categories, nil
+}
+
+// getCategory returns a category by id.
+func (c *CategoryService) getCategory(id int) (models.Category, error) {
+	category, err := c.repo.GetCategoryByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Category{}, ErrCategoryNotFound
+		}
+		return models.Category{}, err
+	}
+	return category, nil
+}
+
+// resolveCategories maps the names or slugs of categories, as picked for a new post, to the names of active categories.
+// Values may hold several categories separated by commas, the same category picked twice counts once.
+func resolveCategories(active []models.Category, values []string) ([]string, error) {
+	var names []string
+	seen := make(map[int]bool)
+	for _, value := range values {
+		for _, picked := range strings.Split(value, ",") {
+			picked = strings.TrimSpace(picked)
+			if picked == "" {
+				continue
+			}
+
+			found := false
+			for _, category := range active {
+				if strings.EqualFold(category.Slug, picked) || strings.EqualFold(category.Name, picked) {
+					if !seen[category.ID] {
+						seen[category.ID] = true
+						names = append(names, category.Name)
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("unknown category %q: %w", picked, ErrInvalidPost)
+			}
+		}
+	}
+	return names, nil
+}
+
+// helper function that validates a models.Category object.
+// It normalizes the name and description to NFC, trims whitespace and checks their lengths in characters,
+// the characters of the name and the slug.
+func isValidCategory(category *models.Category) error {
+	var ok bool
+	if category.Name, ok = normalizeText(strings.TrimSpace(category.Name), ""); !ok {
+		return fmt.Errorf("name contains unsupported characters: %w", ErrInvalidCategory)
+	}
+	if !isValidLength(category.Name, 1, maxCategoryName) {
+		return fmt.Errorf("name length out of range: %w", ErrInvalidCategory)
+	}
+
+	if category.Description, ok = normalizeText(strings.TrimSpace(category.Description), "\r\n"); !ok {
+		return fmt.Errorf("description contains unsupported characters: %w", ErrInvalidCategory)
+	}
+	if !isValidLength(category.Description, 0, maxCategoryDescription) {
+		return fmt.Errorf("description length out of range: %w", ErrInvalidCategory)
+	}
+
+	if category.Position < 0 {
+		return fmt.Errorf("negative position: %w", ErrInvalidCategory)
+	}
+
+	if category.Slug == "" || len(category.Slug) > maxCategorySlug || category.Slug != models.CategorySlug(category.Slug) {
+		return fmt.Errorf("slug must be lower case letters and digits separated by hyphens: %w", ErrInvalidCategory)
+	}
+	return nil
+}
@@ -1,22 +1,27 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"forum/internal/models"
 	"forum/internal/repository"
-	"strings"
+	"time"
 )
 
-var ErrInvalidComment = errors.New("invalid comment")
+var (
+	ErrInvalidComment  = errors.New("invalid comment")
+	ErrCommentNotFound = errors.New("comment not found")
+)
 
 // An interface that defines methods for managing comment data. It is implemented by the CommentService struct.
 type Comment interface {
 	CreateComment(comment *models.Comment) error
 	GetComments(postID int) ([]*models.Comment, error)
 	GetCommentByID(commentID int) (models.Comment, error)
-	LikeComment(commentID int, username string) error
-	DislikeComment(commentID int, username string) error
+	UpdateComment(actor models.User, commentID int, text string) (models.Comment, error)
+	DeleteComment(actor models.User, commentID int) (models.Comment, error)
+	GetCommentRevisions(actor models.User, commentID int) ([]models.CommentRevision, error)
 }
 
 type CommentService struct {
@@ -33,6 +38,15 @@ func (c *CommentService) CreateComment(comment *models.Comment) error {
 		return err
 	}
 
+	if comment.ParentID != 0 {
+		if err := c.isValidParent(comment); err != nil {
+			return err
+		}
+	}
+
+	comment.CreatedAt = models.Timestamp{Time: time.Now()}
+	comment.UpdatedAt = comment.CreatedAt
+
 	return c.repo.CreateComment(comment)
 }
 
@@ -41,69 +55,120 @@ func (c *CommentService) GetComments(postID int) ([]*models.Comment, error) {
 	return c.repo.GetComments(postID)
 }
 
+// GetCommentByID returns a comment by its ID.
 func (c *CommentService) GetCommentByID(commentID int) (models.Comment, error) {
-	return c.repo.GetCommentByID(commentID)
+	comment, err := c.repo.GetCommentByID(commentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Comment{}, ErrCommentNotFound
+		}
+		return models.Comment{}, err
+	}
+	return comment, nil
 }
 
-// LikeComment adds a like to a comment by a specific user.
-func (c *CommentService) LikeComment(commentID int, username string) error {
-	if err := c.repo.CommentHasLike(commentID, username); err == nil {
-		if err := c.repo.RemoveLikeComment(commentID, username); err != nil {
-			return fmt.Errorf("service: like comment: %w", err)
-		}
-		return nil
+// UpdateComment changes the text of a comment the actor may edit and returns the updated comment.
+// The previous text is kept as a revision.
+func (c *CommentService) UpdateComment(actor models.User, commentID int, text string) (models.Comment, error) {
+	comment, err := c.changeableComment(actor, commentID)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("service: update comment: %w", err)
 	}
 
-	if err := c.repo.CommentHasDislike(commentID, username); err == nil {
-		if err := c.repo.RemoveDislikeComment(commentID, username); err != nil {
-			return fmt.Errorf("service: like comment: %w", err)
-		}
+	edited := &models.Comment{Text: text}
+	if err = isValidComment(edited); err != nil {
+		return models.Comment{}, err
+	}
+	if edited.Text == comment.Text {
+		return comment, nil
 	}
 
-	if err := c.repo.LikeComment(commentID, username); err != nil {
-		return fmt.Errorf("service: like comment: %w", err)
+	if err = c.repo.UpdateComment(commentID, edited.Text, actor.ID, time.Now()); err != nil {
+		return models.Comment{}, fmt.Errorf("service: update comment: %w", err)
 	}
 
-	return nil
+	return c.GetCommentByID(commentID)
 }
 
-func (c *CommentService) DislikeComment(commentID int, username string) error {
-	if err := c.repo.CommentHasDislike(commentID, username); err == nil {
-		if err := c.repo.RemoveDislikeComment(commentID, username); err != nil {
-			return fmt.Errorf("service: like comment: %w", err)
-		}
-		return nil
+// DeleteComment deletes a comment the actor may delete and returns it as it was before.
+func (c *CommentService) DeleteComment(actor models.User, commentID int) (models.Comment, error) {
+	comment, err := c.changeableComment(actor, commentID)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("service: delete comment: %w", err)
 	}
-	if err := c.repo.CommentHasLike(commentID, username); err == nil {
-		if err := c.repo.RemoveLikeComment(commentID, username); err != nil {
-			return fmt.Errorf("service: like comment: %w", err)
-		}
+
+	if err = c.repo.DeleteComment(commentID); err != nil {
+		return models.Comment{}, fmt.Errorf("service: delete comment: %w", err)
 	}
 
-	if err := c.repo.DislikeComment(commentID, username); err != nil {
-		return fmt.Errorf("service: like comment: %w", err)
+	return comment, nil
+}
+
+// GetCommentRevisions returns the earlier texts of a comment, the latest edit first.
+// Only moderators may read them.
+func (c *CommentService) GetCommentRevisions(actor models.User, commentID int) ([]models.CommentRevision, error) {
+	if !actor.Role.AtLeast(models.RoleModerator) {
+		return nil, ErrForbidden
 	}
 
-	return nil
+	if _, err := c.GetCommentByID(commentID); err != nil {
+		return nil, err
+	}
+
+	revisions, err := c.repo.GetCommentRevisions(commentID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get comment revisions: %w", err)
+	}
+	return revisions, nil
 }
 
-// isValidComment checks if the comment is valid.
-func isValidComment(comment *models.Comment) error {
-	if len(comment.Text) > 500 {
-		return fmt.Errorf("service: create comment: %w", ErrInvalidComment)
+// changeableComment returns a comment that the actor may edit or delete.
+// Deleted comments count as not found.
+func (c *CommentService) changeableComment(actor models.User, commentID int) (models.Comment, error) {
+	comment, err := c.GetCommentByID(commentID)
+	if err != nil {
+		return models.Comment{}, err
+	}
+	if comment.Deleted {
+		return models.Comment{}, ErrCommentNotFound
 	}
 
-	comment.Text = strings.Trim(comment.Text, " \n\r")
+	if err = authorizeComment(actor, comment); err != nil {
+		return models.Comment{}, err
+	}
+	return comment, nil
+}
 
-	for _, char := range comment.Text {
-		if (char != 13 && char != 10) && (char < 32 || char > 126) {
-			return fmt.Errorf("service: CreatePost: isValidComment err: %w", ErrInvalidComment)
+// isValidParent checks that a reply answers a visible comment of the same post.
+func (c *CommentService) isValidParent(comment *models.Comment) error {
+	parent, err := c.repo.GetCommentByID(comment.ParentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("service: create comment: parent not found: %w", ErrInvalidComment)
 		}
+		return fmt.Errorf("service: create comment: %w", err)
+	}
+
+	if parent.PostID != comment.PostID || parent.Hidden || parent.Deleted {
+		return fmt.Errorf("service: create comment: parent not repliable: %w", ErrInvalidComment)
 	}
 
-	if comment.Text == "" {
+	return nil
+}
+
+// isValidComment checks if the comment is valid.
+// Its text is normalized to NFC and may hold up to 500 characters, but no control characters other than
+// line breaks and tabs, nor the ones that override the direction of text.
+func isValidComment(comment *models.Comment) error {
+	text, ok := normalizeText(trimMarkdown(comment.Text), "\r\n\t")
+	if !ok {
 		return fmt.Errorf("service: CreatePost: isValidComment err: %w", ErrInvalidComment)
 	}
+	comment.Text = text
+
+	if !isValidLength(comment.Text, 1, 500) {
+		return fmt.Errorf("service: create comment: %w", ErrInvalidComment)
+	}
 
 	return nil
 }
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"forum/internal/models"
+	"strings"
+)
+
+// DiffLines compares two texts line by line and returns the lines of both in order,
+// marking the ones that only old has as deleted and the ones that only new has as inserted.
+func DiffLines(old, new string) []models.DiffLine {
+	a, b := splitLines(old), splitLines(new)
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	common := make([][]int, len(a)+1)
+	for i := range common {
+		common[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else if common[i+1][j] >= common[i][j+1] {
+				common[i][j] = common[i+1][j]
+			} else {
+				common[i][j] = common[i][j+1]
+			}
+		}
+	}
+
+	var lines []models.DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, models.DiffLine{Op: models.DiffEqual, Text: a[i]})
+			i++
+			j++
+		case common[i+1][j] >= common[i][j+1]:
+			lines = append(lines, models.DiffLine{Op: models.DiffDelete, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, models.DiffLine{Op: models.DiffInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, models.DiffLine{Op: models.DiffDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, models.DiffLine{Op: models.DiffInsert, Text: b[j]})
+	}
+	return lines
+}
+
+// splitLines splits a text into its lines, ignoring carriage returns.
+func splitLines(text string) []string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidReport   = errors.New("invalid report")
+	ErrAlreadyReported = errors.New("already reported")
+	ErrReportNotFound  = errors.New("report not found")
+	ErrReportClosed    = errors.New("report already resolved")
+	ErrInvalidAction   = errors.New("invalid moderation action")
+)
+
+// resolvedReportsShown is how many past moderation actions the queue shows.
+const resolvedReportsShown = 20
+
+// An interface that defines methods for reporting content and for the moderation queue.
+type Moderation interface {
+	ReportPost(actor models.User, postID int, reason string) error
+	ReportComment(actor models.User, commentID int, reason string) (models.Comment, error)
+	GetModerationQueue(actor models.User) (open, resolved []models.Report, err error)
+	ResolveReport(actor models.User, reportID int, action models.ReportStatus) error
+}
+
+// ModerationService is a struct that implements the Moderation interface.
+type ModerationService struct {
+	repo     repository.Report
+	posts    repository.PostItem
+	comments repository.Comment
+}
+
+// NewModerationService returns a new instance of ModerationService.
+func NewModerationService(repo repository.Report, posts repository.PostItem, comments repository.Comment) *ModerationService {
+	return &ModerationService{repo: repo, posts: posts, comments: comments}
+}
+
+// ReportPost flags a post for moderators.
+// Hidden posts cannot be reported, they count as not found.
+func (m *ModerationService) ReportPost(actor models.User, postID int, reason string) error {
+	post, err := m.posts.GetPostByID(postID)
+	if err != nil {
+		return fmt.Errorf("service: report post: %w: %v", ErrPostNotFound, err)
+	}
+	if post.Hidden {
+		return fmt.Errorf("service: report post: hidden: %w", ErrPostNotFound)
+	}
+
+	return m.createReport(actor, postID, 0, reason)
+}
+
+// ReportComment flags a comment for moderators and returns the reported comment.
+func (m *ModerationService) ReportComment(actor models.User, commentID int, reason string) (models.Comment, error) {
+	comment, err := m.comments.GetCommentByID(commentID)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("service: report comment: %w: %v", ErrCommentNotFound, err)
+	}
+	if comment.Hidden || comment.Deleted {
+		return models.Comment{}, fmt.Errorf("service: report comment: hidden or deleted: %w", ErrCommentNotFound)
+	}
+
+	post, err := m.posts.GetPostByID(comment.PostID)
+	if err != nil {
+		return models.Comment{}, fmt.Errorf("service: report comment: %w: %v", ErrCommentNotFound, err)
+	}
+	if post.Hidden {
+		return models.Comment{}, fmt.Errorf("service: report comment: post hidden: %w", ErrCommentNotFound)
+	}
+
+	return comment, m.createReport(actor, comment.PostID, comment.ID, reason)
+}
+
+func (m *ModerationService) createReport(actor models.User, postID, commentID int, reason string) error {
+	reason, ok := normalizeText(strings.TrimSpace(reason), "\r\n")
+	if !ok || !isValidLength(reason, 1, 300) {
+		return ErrInvalidReport
+	}
+
+	exists, err := m.repo.HasOpenReport(actor.ID, postID, commentID)
+	if err != nil {
+		return fmt.Errorf("service: report: %w", err)
+	}
+	if exists {
+		return ErrAlreadyReported
+	}
+
+	report := &models.Report{
+		ReporterID: actor.ID,
+		PostID:     postID,
+		CommentID:  commentID,
+		Reason:     reason,
+		Status:     models.ReportOpen,
+		CreatedAt:  time.Now(),
+	}
+
+	if err = m.repo.CreateReport(report); err != nil {
+		return fmt.Errorf("service: report: %w", err)
+	}
+	return nil
+}
+
+// GetModerationQueue returns the open reports and the most recent moderation actions.
+func (m *ModerationService) GetModerationQueue(actor models.User) (open, resolved []models.Report, err error) {
+	if !actor.Role.AtLeast(models.RoleModerator) {
+		return nil, nil, ErrForbidden
+	}
+
+	open, err = m.repo.GetReportsByStatus(true, -1)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: get moderation queue: %w", err)
+	}
+
+	resolved, err = m.repo.GetReportsByStatus(false, resolvedReportsShown)
+	if err != nil {
+		return nil, nil, fmt.Errorf("service: get moderation queue: %w", err)
+	}
+
+	return open, resolved, nil
+}
+
+// ResolveReport dismisses an open report or hides or deletes the reported content.
+// Every open report on the same content is closed with the same action, and hiding or deleting a post also closes
+// the open reports on its comments. The reports are closed in the transaction that hides or deletes the content,
+// so they are kept in the moderation log when the content is deleted and stay open if the content does not change.
+func (m *ModerationService) ResolveReport(actor models.User, reportID int, action models.ReportStatus) error {
+	if !actor.Role.AtLeast(models.RoleModerator) {
+		return ErrForbidden
+	}
+
+	report, err := m.repo.GetReportByID(reportID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReportNotFound
+		}
+		return fmt.Errorf("service: resolve report: %w", err)
+	}
+	if report.Status != models.ReportOpen {
+		return ErrReportClosed
+	}
+
+	switch action {
+	case models.ReportDismissed, models.ReportHidden, models.ReportDeleted:
+	default:
+		return ErrInvalidAction
+	}
+
+	now := time.Now()
+	switch {
+	case action == models.ReportDismissed:
+		err = m.repo.ResolveReports(report.PostID, report.CommentID, action, actor.ID, now)
+	case report.CommentID != 0:
+		err = m.comments.ModerateComment(report.CommentID, action, actor.ID, now)
+	default:
+		err = m.posts.ModeratePost(report.PostID, action, actor.ID, now)
+	}
+	if err != nil {
+		return fmt.Errorf("service: resolve report: %w", err)
+	}
+	return nil
+}
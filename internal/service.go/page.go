@@ -0,0 +1,87 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"time"
+)
+
+const (
+	// defaultPageSize is how many posts a page holds when the request does not say.
+	defaultPageSize = 20
+	// maxPageSize is the most posts a single page may hold.
+	maxPageSize = 100
+	// maxFilterCategories is the most categories a post filter may ask for.
+	maxFilterCategories = 10
+)
+
+// ErrInvalidCursor is returned for a page cursor that is malformed or belongs to another sort order.
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// pageCursor is what the opaque cursor of the next page holds.
+type pageCursor struct {
+	Sort  models.PostSort `json:"s"`
+	Now   int64           `json:"t"`
+	After models.PostKey  `json:"a"`
+}
+
+// postListing turns a page request into what the repository needs to list that page.
+func postListing(page models.PageRequest) (models.PostListing, error) {
+	sort, err := validSort(page.Sort)
+	if err != nil {
+		return models.PostListing{}, err
+	}
+
+	limit := page.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	listing := models.PostListing{Sort: sort, Now: time.Now().Unix(), Limit: limit}
+	if page.Cursor == "" {
+		return listing, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(page.Cursor)
+	if err != nil {
+		return models.PostListing{}, fmt.Errorf("service: %w", ErrInvalidCursor)
+	}
+	var cursor pageCursor
+	if err = json.Unmarshal(raw, &cursor); err != nil || cursor.Sort != sort {
+		return models.PostListing{}, fmt.Errorf("service: %w", ErrInvalidCursor)
+	}
+
+	listing.Now = cursor.Now
+	listing.After = &cursor.After
+	return listing, nil
+}
+
+// listingError maps an error of a repository listing to the service errors.
+func listingError(op string, err error) error {
+	if errors.Is(err, repository.ErrPostKeyMismatch) {
+		return fmt.Errorf("service: %s: %w", op, ErrInvalidCursor)
+	}
+	return fmt.Errorf("service: %s: %w", op, err)
+}
+
+// postPage wraps the posts of a listing and the position of its last post into a page.
+func postPage(listing models.PostListing, posts []models.Post, next *models.PostKey) (models.PostPage, error) {
+	page := models.PostPage{Posts: posts}
+	if next == nil {
+		return page, nil
+	}
+
+	raw, err := json.Marshal(pageCursor{Sort: listing.Sort, Now: listing.Now, After: *next})
+	if err != nil {
+		return models.PostPage{}, fmt.Errorf("service: encode cursor: %w", err)
+	}
+	page.Next = base64.RawURLEncoding.EncodeToString(raw)
+	return page, nil
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"forum/internal/models"
+	"forum/internal/repository"
+)
+
+// TestPageCursorRoundTrip checks that the cursor of a page brings back the listing of the page after it.
+func TestPageCursorRoundTrip(t *testing.T) {
+	for _, sort := range models.PostSorts {
+		first, err := postListing(models.PageRequest{Sort: sort, Limit: 5})
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := models.PostKey{Values: []float64{3, 0.1 + 0.2, 1e-7}, ID: 42}
+		page, err := postPage(first, nil, &after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Next == "" {
+			t.Fatalf("%s: page has no next cursor", sort)
+		}
+
+		next, err := postListing(models.PageRequest{Sort: sort, Cursor: page.Next, Limit: 5})
+		if err != nil {
+			t.Fatalf("%s: %v", sort, err)
+		}
+		want := first
+		want.After = &after
+		if !reflect.DeepEqual(next, want) {
+			t.Errorf("%s: listing after the cursor = %+v, want %+v", sort, next, want)
+		}
+	}
+}
+
+func TestPageCursorLastPage(t *testing.T) {
+	listing, err := postListing(models.PageRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := postPage(listing, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Next != "" {
+		t.Errorf("last page has the next cursor %q", page.Next)
+	}
+}
+
+// TestPageCursorTampered checks that cursors that were not handed out for the listing are refused.
+func TestPageCursorTampered(t *testing.T) {
+	listing, err := postListing(models.PageRequest{Sort: models.SortTop})
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := postPage(listing, nil, &models.PostKey{Values: []float64{3}, ID: 42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(page.Next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	truncated := base64.RawURLEncoding.EncodeToString(raw[:len(raw)-1])
+	encode := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+
+	tests := []struct {
+		name   string
+		sort   models.PostSort
+		cursor string
+	}{
+		{"other sort", models.SortNew, page.Next},
+		{"not base64", models.SortTop, "!" + page.Next},
+		{"padded base64", models.SortTop, base64.URLEncoding.EncodeToString(raw) + "=="},
+		{"truncated", models.SortTop, truncated},
+		{"not json", models.SortTop, encode("top")},
+		{"no sort", models.SortTop, encode(`{"t":1,"a":{"v":[3],"id":42}}`)},
+		{"wrong type", models.SortTop, encode(`{"s":"top","t":1,"a":{"v":"3","id":42}}`)},
+	}
+	for _, tt := range tests {
+		if _, err := postListing(models.PageRequest{Sort: tt.sort, Cursor: tt.cursor}); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("%s: err = %v, want ErrInvalidCursor", tt.name, err)
+		}
+	}
+}
+
+// TestListingErrorKeyMismatch checks that a cursor whose position does not fit its sort order, which only the
+// repository can tell, is reported as an invalid cursor too.
+func TestListingErrorKeyMismatch(t *testing.T) {
+	err := listingError("get all posts", fmt.Errorf("storage: list posts: %w", repository.ErrPostKeyMismatch))
+	if !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("err = %v, want ErrInvalidCursor", err)
+	}
+	if err = listingError("get all posts", errors.New("disk full")); errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("err = %v, want an error other than ErrInvalidCursor", err)
+	}
+}
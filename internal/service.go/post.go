@@ -1,224 +1,353 @@
 package service
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"forum/internal/models"
 	"forum/internal/repository"
 	"strings"
+	"time"
 )
 
-// A custom error that is returned when a post fails to meet validation criteria.
-var ErrInvalidPost = errors.New("invalid post")
+var (
+	// A custom error that is returned when a post fails to meet validation criteria.
+	ErrInvalidPost = errors.New("invalid post")
+	// A custom error that is returned when a post does not exist.
+	ErrPostNotFound = errors.New("post not found")
+	// A custom error that is returned when a listing is asked for an unknown order.
+	ErrInvalidSort = errors.New("invalid sort order")
+	// A custom error that is returned when a post filter asks for too many categories.
+	ErrInvalidFilter = errors.New("invalid filter")
+)
 
 // An interface that defines methods for managing post data. It is implemented by the PostService struct.
 type PostItem interface {
-	CreatePost(post *models.Post) error
-	GetAllPosts() (posts []models.Post, err error)
-	GetPostsByCategory(category string) ([]models.Post, error)
-	GetCreatedPosts(userID int) ([]models.Post, error)
-	GetLikedPosts(username string) ([]models.Post, error)
+	CreatePost(post *models.Post, uploads []models.Upload) error
+	GetAllPosts(page models.PageRequest) (models.PostPage, error)
+	GetCreatedPosts(userID int, page models.PageRequest) (models.PostPage, error)
+	GetLikedPosts(username string, page models.PageRequest) (models.PostPage, error)
+	GetSubscribedPosts(userID int, page models.PageRequest) (models.PostPage, error)
+	FilterPosts(filter models.PostFilter, page models.PageRequest) (models.PostPage, error)
 	GetPostByID(id int) (models.Post, error)
-	UpdatePost(id, like, dislike int, title, content string) error
-	DeletePost(id int) error
-	LikePost(username string, postid int) error
-	DisLikePost(username string, postid int) error
+	AuthorizePostChange(actor models.User, post models.Post) error
+	UpdatePost(actor models.User, id int, title, content string, tags []string) error
+	GetPostHistory(postID int) ([]models.PostRevision, error)
+	DeletePost(actor models.User, id int) error
 }
 
 type PostService struct {
-	repo repository.PostItem
+	repo        repository.PostItem
+	categories  repository.CategoryItem
+	attachments repository.AttachmentItem
 }
 
 // NewPostService returns a new instance of PostService.
-func NewPostService(repo repository.PostItem) *PostService {
-	return &PostService{repo: repo}
+func NewPostService(repo repository.PostItem, categories repository.CategoryItem, attachments repository.AttachmentItem) *PostService {
+	return &PostService{repo: repo, categories: categories, attachments: attachments}
 }
 
-// CreatePost creates a new post in the database.
-func (p *PostService) CreatePost(post *models.Post) error {
-	post.Category = strings.Split(post.Category[0], ",")
-
-	if err := isValidPost(post); err != nil {
+// CreatePost creates a new post in the database, with the uploaded files attached to it.
+// Its categories are picked by name or slug among the categories that are not archived, its tags are normalized.
+// The post is deleted again when one of its attachments cannot be stored.
+func (p *PostService) CreatePost(post *models.Post, uploads []models.Upload) error {
+	active, err := p.categories.ListCategories(false)
+	if err != nil {
+		return fmt.Errorf("service: create post: %w", err)
+	}
+	if post.Category, err = resolveCategories(active, post.Category); err != nil {
+		return err
+	}
+	if post.Tags, err = normalizeTags(post.Tags); err != nil {
 		return err
 	}
 
-	return p.repo.CreatePost(post)
-}
+	if err = isValidPost(post); err != nil {
+		return err
+	}
 
-// GetAllPosts returns all posts from the database.
-func (p *PostService) GetAllPosts() ([]models.Post, error) {
-	posts, err := p.repo.GetAllPosts()
+	attachments, err := prepareAttachments(uploads)
 	if err != nil {
-		return []models.Post{}, err
+		return err
 	}
 
-	for i := range posts {
-		category, err := p.repo.GetCategoriesByPostID(posts[i].Id)
-		if err != nil {
-			return nil, fmt.Errorf("service: get all post: %w", err)
-		}
-		posts[i].Category = category
+	post.CreatedAt = models.Timestamp{Time: time.Now()}
+	post.UpdatedAt = post.CreatedAt
+
+	if err = p.repo.CreatePost(post); err != nil {
+		return err
 	}
 
-	return posts, nil
+	post.Attachments = nil
+	for _, prepared := range attachments {
+		attachment := prepared.attachment
+		attachment.PostID = post.Id
+		attachment.CreatedAt = post.CreatedAt
+		if err = p.attachments.CreateAttachment(&attachment, prepared.file, prepared.thumbnail, prepared.fileExt, prepared.thumbnailExt); err != nil {
+			if deleteErr := p.repo.DeletePost(post.Id); deleteErr != nil {
+				return fmt.Errorf("service: create post: %w, and deleting the post: %v", err, deleteErr)
+			}
+			return fmt.Errorf("service: create post: %w", err)
+		}
+		post.Attachments = append(post.Attachments, attachment)
+	}
+	return nil
 }
 
-// GetPostsByCategory returns all posts from the database by category.
-func (p *PostService) GetPostsByCategory(category string) ([]models.Post, error) {
-	posts, err := p.repo.GetPostsByCategory(category)
+// GetAllPosts returns a page of all posts.
+func (p *PostService) GetAllPosts(page models.PageRequest) (models.PostPage, error) {
+	listing, err := postListing(page)
 	if err != nil {
-		return []models.Post{}, err
+		return models.PostPage{}, err
 	}
 
-	for i := range posts {
-		category, err := p.repo.GetCategoriesByPostID(posts[i].Id)
-		if err != nil {
-			return nil, fmt.Errorf("service: get all post: %w", err)
-		}
-		posts[i].Category = category
+	posts, next, err := p.repo.GetAllPosts(listing)
+	if err != nil {
+		return models.PostPage{}, listingError("get all posts", err)
 	}
 
-	return posts, nil
+	return postPage(listing, posts, next)
 }
 
-// GetCreatedPosts returns all posts from the database by user id.
-func (p *PostService) GetCreatedPosts(userID int) ([]models.Post, error) {
-	posts, err := p.repo.GetCreatedPosts(userID)
+// GetCreatedPosts returns a page of the posts created by a user.
+func (p *PostService) GetCreatedPosts(userID int, page models.PageRequest) (models.PostPage, error) {
+	listing, err := postListing(page)
 	if err != nil {
-		return []models.Post{}, err
+		return models.PostPage{}, err
 	}
 
-	for i := range posts {
-		category, err := p.repo.GetCategoriesByPostID(posts[i].Id)
-		if err != nil {
-			return nil, fmt.Errorf("service: get all post: %w", err)
-		}
-		posts[i].Category = category
+	posts, next, err := p.repo.GetCreatedPosts(userID, listing)
+	if err != nil {
+		return models.PostPage{}, listingError("get created posts", err)
 	}
 
-	return posts, nil
+	return postPage(listing, posts, next)
 }
 
-// GetLikedPosts returns all posts from the database by user id.
-func (p *PostService) GetLikedPosts(username string) ([]models.Post, error) {
-	posts, err := p.repo.GetLikedPosts(username)
+// GetLikedPosts returns a page of the posts liked by a user.
+func (p *PostService) GetLikedPosts(username string, page models.PageRequest) (models.PostPage, error) {
+	listing, err := postListing(page)
 	if err != nil {
-		return []models.Post{}, err
+		return models.PostPage{}, err
 	}
 
-	for i := range posts {
-		category, err := p.repo.GetCategoriesByPostID(posts[i].Id)
-		if err != nil {
-			return nil, fmt.Errorf("service: get all post: %w", err)
-		}
-		posts[i].Category = category
+	posts, next, err := p.repo.GetLikedPosts(username, listing)
+	if err != nil {
+		return models.PostPage{}, listingError("get liked posts", err)
 	}
-	return posts, nil
+
+	return postPage(listing, posts, next)
 }
 
-// GetPostByID returns a post from the database by id.
-func (p *PostService) GetPostByID(id int) (posts models.Post, err error) {
-	post, err := p.repo.GetPostByID(id)
+// GetSubscribedPosts returns a page of the posts filed under the categories that a user subscribed to.
+// The feed always shows the newest posts first, whatever order the page asks for.
+func (p *PostService) GetSubscribedPosts(userID int, page models.PageRequest) (models.PostPage, error) {
+	page.Sort = models.SortNew
+	listing, err := postListing(page)
 	if err != nil {
-		return models.Post{}, err
+		return models.PostPage{}, err
 	}
 
-	post.Category, err = p.repo.GetCategoriesByPostID(id)
+	posts, next, err := p.repo.GetSubscribedPosts(userID, listing)
 	if err != nil {
-		return models.Post{}, err
+		return models.PostPage{}, listingError("get subscribed posts", err)
 	}
 
-	return post, nil
+	return postPage(listing, posts, next)
 }
 
-// LikePost adds a like to a post.
-func (p *PostService) LikePost(username string, postid int) error {
-	if err := p.repo.HasUserLiked(username, postid); err != nil {
-		if err = p.repo.HasUserDislike(username, postid); err == nil {
-			if err = p.repo.RemoveDisLikePost(postid); err != nil {
-				return err
-			}
+// FilterPosts returns a page of the posts that match a filter.
+// The same category asked for twice counts once, at most maxFilterCategories different ones may be asked for.
+func (p *PostService) FilterPosts(filter models.PostFilter, page models.PageRequest) (models.PostPage, error) {
+	var categoryIDs []int
+	seen := make(map[int]bool)
+	for _, id := range filter.CategoryIDs {
+		if !seen[id] {
+			seen[id] = true
+			categoryIDs = append(categoryIDs, id)
 		}
-		return p.repo.LikePost(username, postid)
+	}
+	if len(categoryIDs) > maxFilterCategories {
+		return models.PostPage{}, fmt.Errorf("more than %d categories: %w", maxFilterCategories, ErrInvalidFilter)
+	}
+	filter.CategoryIDs = categoryIDs
+
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return models.PostPage{}, fmt.Errorf("%v: %w", err, ErrInvalidFilter)
+	}
+	filter.Tags = tags
+
+	listing, err := postListing(page)
+	if err != nil {
+		return models.PostPage{}, err
+	}
 
+	posts, next, err := p.repo.FilterPosts(filter, listing)
+	if err != nil {
+		return models.PostPage{}, listingError("filter posts", err)
 	}
 
-	return p.repo.RemoveLikePost(postid)
+	return postPage(listing, posts, next)
 }
 
-func (p *PostService) DisLikePost(username string, postid int) error {
-	if err := p.repo.HasUserDislike(username, postid); err != nil {
-		if err := p.repo.HasUserLiked(username, postid); err == nil {
-			if err = p.repo.RemoveLikePost(postid); err != nil {
-				return err
-			}
+// GetPostByID returns a post from the database by id.
+func (p *PostService) GetPostByID(id int) (posts models.Post, err error) {
+	post, err := p.repo.GetPostByID(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.Post{}, ErrPostNotFound
 		}
-		return p.repo.DisLikePost(username, postid)
+		return models.Post{}, err
+	}
 
+	if post.Attachments, err = p.attachments.GetAttachmentsByPostID(id); err != nil {
+		return models.Post{}, fmt.Errorf("service: get post by id: %w", err)
+	}
+
+	return post, nil
+}
+
+// validSort checks the sort order asked for a listing, no order at all means the newest posts first.
+func validSort(sort models.PostSort) (models.PostSort, error) {
+	if sort == "" {
+		return models.SortNew, nil
+	}
+	if !sort.IsValid() {
+		return "", fmt.Errorf("service: %q: %w", sort, ErrInvalidSort)
 	}
-	return p.repo.RemoveDisLikePost(postid)
+	return sort, nil
 }
 
 // helper function that validates a models.Post object.
-// It checks that the title, content, and about fields are not empty and do not exceed predefined length limits.
-// It also trims whitespace and ensures that only printable ASCII characters are used.
+// It normalizes the title, content, and about fields to NFC, trims whitespace and checks that they are not empty
+// and do not exceed predefined length limits, counted in characters. Control characters and the ones that
+// override the direction of text are rejected, the content may hold tabs to indent code.
 func isValidPost(post *models.Post) error {
-	if len(post.Title) > 100 {
-		return errors.New("title length out of range")
+	if len(post.Category) == 0 {
+		return fmt.Errorf("no category selected: %w", ErrInvalidPost)
 	}
 
-	if len(post.About) > 300 {
-		return errors.New("content length out of range")
+	if err := isValidPostText(post); err != nil {
+		return err
 	}
 
-	if len(post.Content) > 1500 {
-		return errors.New("content length out of range")
+	var ok bool
+	if post.About, ok = normalizeText(strings.Trim(post.About, " \n\r"), "\r\n"); !ok {
+		return fmt.Errorf("about contains unsupported characters: %w", ErrInvalidPost)
+	}
+	if !isValidLength(post.About, 1, 300) {
+		return fmt.Errorf("about length out of range: %w", ErrInvalidPost)
 	}
 
-	post.Title = strings.Trim(post.Title, " \n\r")
+	return nil
+}
 
-	for _, char := range post.Title {
-		if (char != 13 && char != 10) && (char < 32 || char > 126) {
-			return ErrInvalidPost
-		}
+// isValidPostText checks the title and content of a post, the parts that editing a post changes.
+func isValidPostText(post *models.Post) error {
+	var ok bool
+	if post.Title, ok = normalizeText(strings.Trim(post.Title, " \n\r"), "\r\n"); !ok {
+		return fmt.Errorf("title contains unsupported characters: %w", ErrInvalidPost)
+	}
+	if !isValidLength(post.Title, 1, 100) {
+		return fmt.Errorf("title length out of range: %w", ErrInvalidPost)
 	}
 
-	if post.Title == "" {
-		return ErrInvalidPost
+	if post.Content, ok = normalizeText(trimMarkdown(post.Content), "\r\n\t"); !ok {
+		return fmt.Errorf("content contains unsupported characters: %w", ErrInvalidPost)
+	}
+	if !isValidLength(post.Content, 1, 1500) {
+		return fmt.Errorf("content length out of range: %w", ErrInvalidPost)
 	}
 
-	post.About = strings.Trim(post.About, " \n\r")
+	return nil
+}
 
-	for _, char := range post.About {
-		if (char != 13 && char != 10) && (char < 32 || char > 126) {
-			return ErrInvalidPost
+// trimMarkdown trims the blank lines before markdown text and the whitespace after it.
+// The spaces that start its first line are kept, they may make it a code block.
+func trimMarkdown(text string) string {
+	text = strings.TrimRight(text, " \t\n\r")
+	for {
+		end := strings.IndexByte(text, '\n')
+		if end < 0 || strings.TrimSpace(text[:end]) != "" {
+			return text
 		}
+		text = text[end+1:]
 	}
+}
 
-	if post.About == "" {
-		return ErrInvalidPost
+// AuthorizePostChange checks that actor may edit or delete post.
+func (p *PostService) AuthorizePostChange(actor models.User, post models.Post) error {
+	return authorizePost(actor, post)
+}
+
+// UpdatePost changes the title and content of a post owned by actor, and its tags unless tags is nil.
+// Whoever may edit the post may change its tags. Nothing is saved unless every change is valid.
+func (p *PostService) UpdatePost(actor models.User, id int, title, content string, tags []string) error {
+	post, err := p.GetPostByID(id)
+	if err != nil {
+		return fmt.Errorf("service: update post: %w", err)
 	}
 
-	post.Content = strings.Trim(post.Content, " \n\r")
+	if err = authorizePost(actor, post); err != nil {
+		return fmt.Errorf("service: update post: %w", err)
+	}
 
-	for _, char := range post.Content {
-		if (char != 13 && char != 10) && (char < 32 || char > 126) {
-			return ErrInvalidPost
+	previous := post
+	post.Title = title
+	post.Content = content
+	if err = isValidPostText(&post); err != nil {
+		return fmt.Errorf("service: update post: %w", err)
+	}
+	if tags != nil {
+		if tags, err = normalizeTags(tags); err != nil {
+			return fmt.Errorf("service: update post: %w", err)
+		}
+		if tags == nil {
+			tags = []string{}
 		}
 	}
-
-	if post.Content == "" {
-		return ErrInvalidPost
+	if post.Title == previous.Title && post.Content == previous.Content && tags == nil {
+		return nil
 	}
 
-	return nil
+	return p.repo.UpdatePost(id, post.Title, post.Content, tags, actor.ID, time.Now())
 }
 
-// Proxy methods to the corresponding repository methods to update or delete a post.
-func (p *PostService) UpdatePost(id, like, dislike int, title, content string) error {
-	return p.repo.UpdatePost(id, like, dislike, title, content)
+// GetPostHistory returns every version of a post, the oldest first and the current one last.
+func (p *PostService) GetPostHistory(postID int) ([]models.PostRevision, error) {
+	post, err := p.GetPostByID(postID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get post history: %w", err)
+	}
+
+	revisions, err := p.repo.GetPostRevisions(postID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get post history: %w", err)
+	}
+
+	revisions = append(revisions, models.PostRevision{
+		PostID:  post.Id,
+		Title:   post.Title,
+		Content: post.Content,
+	})
+	for i := range revisions {
+		revisions[i].Version = i + 1
+	}
+
+	return revisions, nil
 }
 
-func (p *PostService) DeletePost(id int) error {
+// DeletePost deletes a post owned by actor.
+func (p *PostService) DeletePost(actor models.User, id int) error {
+	post, err := p.GetPostByID(id)
+	if err != nil {
+		return fmt.Errorf("service: delete post: %w", err)
+	}
+
+	if err = authorizePost(actor, post); err != nil {
+		return fmt.Errorf("service: delete post: %w", err)
+	}
+
 	return p.repo.DeletePost(id)
 }
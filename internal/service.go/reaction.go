@@ -0,0 +1,213 @@
+package service
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+)
+
+// reactionUsersPageSize is how many users a page of the users who gave a reaction lists.
+const reactionUsersPageSize = 20
+
+// maxReactionUsersPage is the last page of the users who gave a reaction that can be asked for, so that the
+// offset of a page always fits in an int.
+const maxReactionUsersPage = 10000
+
+var (
+	// A custom error that is returned for an emoji that is not one of the reactions users can give.
+	ErrInvalidReaction = errors.New("invalid reaction")
+	// A custom error that is returned for a page of reaction users past maxReactionUsersPage.
+	ErrInvalidPage = errors.New("invalid page")
+)
+
+// An interface that defines methods for reacting to posts and comments. It is implemented by the ReactionService struct.
+// Liking and disliking are the reactions models.ReactionLike and models.ReactionDislike.
+type ReactionItem interface {
+	LikePost(username string, postid int) error
+	DisLikePost(username string, postid int) error
+	LikeComment(commentID int, username string) error
+	DislikeComment(commentID int, username string) error
+	ReactToPost(username string, postID int, emoji string) error
+	ReactToComment(username string, commentID int, emoji string) error
+	GetPostReactions(viewer string, postID int) ([]models.Reaction, error)
+	SetCommentReactions(viewer string, comments []*models.Comment) error
+	GetPostReactionUsers(postID int, emoji string, page int) (models.ReactionUserPage, error)
+	GetCommentReactionUsers(commentID int, emoji string, page int) (models.ReactionUserPage, error)
+}
+
+type ReactionService struct {
+	repo repository.ReactionItem
+	// emoji are the reactions users can give besides liking and disliking.
+	emoji []string
+}
+
+// NewReactionService returns a new instance of ReactionService that offers the reactions emoji besides liking and disliking.
+func NewReactionService(repo repository.ReactionItem, emoji []string) *ReactionService {
+	return &ReactionService{repo: repo, emoji: emoji}
+}
+
+// LikePost likes a post for a user, or takes the like back when the user liked the post already.
+// A dislike of the user is replaced by the like.
+func (r *ReactionService) LikePost(username string, postid int) error {
+	return r.ReactToPost(username, postid, models.ReactionLike)
+}
+
+// DisLikePost dislikes a post for a user, or takes the dislike back when the user disliked the post already.
+// A like of the user is replaced by the dislike.
+func (r *ReactionService) DisLikePost(username string, postid int) error {
+	return r.ReactToPost(username, postid, models.ReactionDislike)
+}
+
+// LikeComment likes a comment for a user, or takes the like back when the user liked the comment already.
+// A dislike of the user is replaced by the like.
+func (r *ReactionService) LikeComment(commentID int, username string) error {
+	return r.ReactToComment(username, commentID, models.ReactionLike)
+}
+
+// DislikeComment dislikes a comment for a user, or takes the dislike back when the user disliked the comment already.
+// A like of the user is replaced by the dislike.
+func (r *ReactionService) DislikeComment(commentID int, username string) error {
+	return r.ReactToComment(username, commentID, models.ReactionDislike)
+}
+
+// ReactToPost gives the reaction of a user to a post, or takes it back when the user gave it already.
+func (r *ReactionService) ReactToPost(username string, postID int, emoji string) error {
+	emoji, err := r.reaction(emoji)
+	if err != nil {
+		return err
+	}
+	if err = r.repo.TogglePostReaction(username, postID, emoji); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPostNotFound
+		}
+		return fmt.Errorf("service: react to post: %w", err)
+	}
+	return nil
+}
+
+// ReactToComment gives the reaction of a user to a comment, or takes it back when the user gave it already.
+func (r *ReactionService) ReactToComment(username string, commentID int, emoji string) error {
+	emoji, err := r.reaction(emoji)
+	if err != nil {
+		return err
+	}
+	if err = r.repo.ToggleCommentReaction(username, commentID, emoji); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		return fmt.Errorf("service: react to comment: %w", err)
+	}
+	return nil
+}
+
+// GetPostReactions returns the reactions to a post, see offered for their order.
+func (r *ReactionService) GetPostReactions(viewer string, postID int) ([]models.Reaction, error) {
+	reactions, err := r.repo.GetPostReactions(postID, viewer)
+	if err != nil {
+		return nil, fmt.Errorf("service: get post reactions: %w", err)
+	}
+	return r.offered(reactions), nil
+}
+
+// SetCommentReactions sets the reactions to comments of one post, see offered for their order.
+func (r *ReactionService) SetCommentReactions(viewer string, comments []*models.Comment) error {
+	if len(comments) == 0 {
+		return nil
+	}
+	reactions, err := r.repo.GetCommentReactions(comments[0].PostID, viewer)
+	if err != nil {
+		return fmt.Errorf("service: set comment reactions: %w", err)
+	}
+	for _, comment := range comments {
+		comment.Reactions = r.offered(reactions[comment.ID])
+	}
+	return nil
+}
+
+// GetPostReactionUsers returns a page of the users who gave a reaction to a post, the latest first.
+// Pages count from 1 up to maxReactionUsersPage.
+func (r *ReactionService) GetPostReactionUsers(postID int, emoji string, page int) (models.ReactionUserPage, error) {
+	result, err := r.users(r.repo.GetPostReactionUsers, postID, emoji, page)
+	if err != nil && !errors.Is(err, ErrInvalidReaction) && !errors.Is(err, ErrInvalidPage) {
+		return result, fmt.Errorf("service: get post reaction users: %w", err)
+	}
+	return result, err
+}
+
+// GetCommentReactionUsers returns a page of the users who gave a reaction to a comment, the latest first.
+// Pages count from 1 up to maxReactionUsersPage.
+func (r *ReactionService) GetCommentReactionUsers(commentID int, emoji string, page int) (models.ReactionUserPage, error) {
+	result, err := r.users(r.repo.GetCommentReactionUsers, commentID, emoji, page)
+	if err != nil && !errors.Is(err, ErrInvalidReaction) && !errors.Is(err, ErrInvalidPage) {
+		return result, fmt.Errorf("service: get comment reaction users: %w", err)
+	}
+	return result, err
+}
+
+func (r *ReactionService) users(list func(int, string, int, int) ([]models.ReactionUser, error), id int, emoji string, page int) (models.ReactionUserPage, error) {
+	emoji, err := r.reaction(emoji)
+	if err != nil {
+		return models.ReactionUserPage{}, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > maxReactionUsersPage {
+		return models.ReactionUserPage{}, fmt.Errorf("page %d: %w", page, ErrInvalidPage)
+	}
+
+	users, err := list(id, emoji, reactionUsersPageSize+1, (page-1)*reactionUsersPageSize)
+	if err != nil {
+		return models.ReactionUserPage{}, err
+	}
+
+	result := models.ReactionUserPage{Emoji: emoji, Users: users, Page: page}
+	if len(users) > reactionUsersPageSize {
+		result.Users, result.More = users[:reactionUsersPageSize], true
+	}
+	if result.Users == nil {
+		result.Users = []models.ReactionUser{}
+	}
+	return result, nil
+}
+
+// offered lists the reactions that users can give, in the order they are configured and with a zero count when
+// nobody gave them, followed by the other reactions that were given: likes, dislikes and the reactions that are
+// no longer offered.
+func (r *ReactionService) offered(given []models.Reaction) []models.Reaction {
+	reactions := make([]models.Reaction, 0, len(r.emoji)+len(given))
+	taken := make([]bool, len(given))
+	for _, emoji := range r.emoji {
+		reaction := models.Reaction{Emoji: emoji, Users: []string{}}
+		for i, g := range given {
+			if g.Emoji == emoji {
+				reaction, taken[i] = g, true
+			}
+		}
+		reactions = append(reactions, reaction)
+	}
+	for i, g := range given {
+		if !taken[i] {
+			reactions = append(reactions, g)
+		}
+	}
+	return reactions
+}
+
+// reaction returns emoji the way it is stored when users can react with it. Emoji are compared without the
+// variation selector that asks for the emoji presentation, which keyboards do not always add.
+func (r *ReactionService) reaction(emoji string) (string, error) {
+	if emoji == models.ReactionLike || emoji == models.ReactionDislike {
+		return emoji, nil
+	}
+	bare := strings.ReplaceAll(emoji, "\ufe0f", "")
+	for _, offered := range r.emoji {
+		if strings.ReplaceAll(offered, "\ufe0f", "") == bare {
+			return offered, nil
+		}
+	}
+	return "", fmt.Errorf("service: %q: %w", emoji, ErrInvalidReaction)
+}
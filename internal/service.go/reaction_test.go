@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"forum/internal/models"
+)
+
+// TestReactionUsersPages checks the offset asked for each page of reaction users and that pages past the last one
+// that can be asked for are refused before any offset is computed.
+func TestReactionUsersPages(t *testing.T) {
+	r := NewReactionService(nil, nil)
+	tests := []struct {
+		page   int
+		offset int
+		err    error
+	}{
+		{-1, 0, nil},
+		{0, 0, nil},
+		{1, 0, nil},
+		{3, 2 * reactionUsersPageSize, nil},
+		{maxReactionUsersPage, (maxReactionUsersPage - 1) * reactionUsersPageSize, nil},
+		{maxReactionUsersPage + 1, -1, ErrInvalidPage},
+		{math.MaxInt, -1, ErrInvalidPage},
+	}
+	for _, tt := range tests {
+		offset := -1
+		list := func(id int, emoji string, limit, skip int) ([]models.ReactionUser, error) {
+			offset = skip
+			return nil, nil
+		}
+		_, err := r.users(list, 1, models.ReactionLike, tt.page)
+		if !errors.Is(err, tt.err) || offset != tt.offset {
+			t.Errorf("page %d: offset %d and err %v, want %d and %v", tt.page, offset, err, tt.offset, tt.err)
+		}
+	}
+}
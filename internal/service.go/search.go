@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+)
+
+var (
+	// A custom error that is returned when a search has no words to look for or too many.
+	ErrInvalidSearch = errors.New("invalid search")
+	// A custom error that is returned when the server was built without full-text search.
+	ErrSearchUnavailable = errors.New("search is not available")
+)
+
+const (
+	// searchPageSize is the number of results on a page of search results.
+	searchPageSize = 20
+	// maxSearchLength is the longest search text that is accepted, in bytes.
+	maxSearchLength = 200
+	// maxSearchTerms is the largest number of words and phrases in a search.
+	maxSearchTerms = 16
+)
+
+// An interface that defines methods for searching posts and comments. It is implemented by the SearchService struct.
+type SearchIndex interface {
+	Search(request models.SearchRequest) (models.SearchPage, error)
+}
+
+type SearchService struct {
+	repo repository.SearchIndex
+}
+
+// NewSearchService returns a new instance of SearchService.
+func NewSearchService(repo repository.SearchIndex) *SearchService {
+	return &SearchService{repo: repo}
+}
+
+// Search returns a page of the visible posts and comments that contain every word and phrase of the request.
+func (s *SearchService) Search(request models.SearchRequest) (models.SearchPage, error) {
+	match, err := searchMatch(request.Query)
+	if err != nil {
+		return models.SearchPage{}, err
+	}
+	if request.Page < 0 {
+		return models.SearchPage{}, fmt.Errorf("service: page %d: %w", request.Page, ErrInvalidSearch)
+	}
+	if request.Page == 0 {
+		request.Page = 1
+	}
+	request.Author = strings.TrimSpace(request.Author)
+
+	results, err := s.repo.Search(match, request, searchPageSize+1, (request.Page-1)*searchPageSize)
+	if err != nil {
+		if errors.Is(err, repository.ErrSearchUnavailable) {
+			return models.SearchPage{}, ErrSearchUnavailable
+		}
+		return models.SearchPage{}, fmt.Errorf("service: search: %w", err)
+	}
+
+	page := models.SearchPage{Results: results, Page: request.Page}
+	if len(results) > searchPageSize {
+		page.Results = results[:searchPageSize]
+		page.More = true
+	}
+	return page, nil
+}
+
+// searchMatch turns a search into an FTS5 query that matches every word and every phrase in double quotes.
+// Each term is quoted so that FTS5 operators typed into the search are looked for as plain words,
+// a word ending in * still matches every word that starts with it.
+func searchMatch(query string) (string, error) {
+	if len(query) > maxSearchLength {
+		return "", fmt.Errorf("service: search longer than %d bytes: %w", maxSearchLength, ErrInvalidSearch)
+	}
+
+	var terms []string
+	for i, part := range strings.Split(query, `"`) {
+		// Odd parts are between double quotes, an unclosed quote runs to the end of the search.
+		if i%2 == 1 {
+			if phrase := strings.Join(strings.Fields(part), " "); phrase != "" {
+				terms = append(terms, `"`+phrase+`"`)
+			}
+			continue
+		}
+		for _, word := range strings.Fields(part) {
+			prefix := strings.HasSuffix(word, "*")
+			word = strings.TrimRight(word, "*")
+			if word == "" {
+				continue
+			}
+			term := `"` + word + `"`
+			if prefix {
+				term += "*"
+			}
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return "", fmt.Errorf("service: nothing to search for: %w", ErrInvalidSearch)
+	}
+	if len(terms) > maxSearchTerms {
+		return "", fmt.Errorf("service: more than %d search terms: %w", maxSearchTerms, ErrInvalidSearch)
+	}
+	return strings.Join(terms, " "), nil
+}
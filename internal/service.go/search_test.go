@@ -0,0 +1,39 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSearchMatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+		err   error
+	}{
+		{"words", "cats dogs", `"cats" "dogs"`, nil},
+		{"operators", "cats AND dogs OR NOT birds", `"cats" "AND" "dogs" "OR" "NOT" "birds"`, nil},
+		{"near", "NEAR(cats dogs)", `"NEAR(cats" "dogs)"`, nil},
+		{"column filter", "title:cats", `"title:cats"`, nil},
+		{"minus and caret", "-cats ^dogs", `"-cats" "^dogs"`, nil},
+		{"prefix", "cat*", `"cat"*`, nil},
+		{"star in a word", "c*t", `"c*t"`, nil},
+		{"phrase", `"cats  and dogs" birds`, `"cats and dogs" "birds"`, nil},
+		{"unclosed phrase", `birds "cats and`, `"birds" "cats and"`, nil},
+		{"quote in a word", `ca"ts`, `"ca" "ts"`, nil},
+		{"star", "*", "", ErrInvalidSearch},
+		{"quote", `"`, "", ErrInvalidSearch},
+		{"empty phrase", `"  " **`, "", ErrInvalidSearch},
+		{"blank", "  ", "", ErrInvalidSearch},
+		{"too many terms", strings.Repeat("cat ", maxSearchTerms+1), "", ErrInvalidSearch},
+		{"too long", strings.Repeat("c", maxSearchLength+1), "", ErrInvalidSearch},
+	}
+	for _, tt := range tests {
+		got, err := searchMatch(tt.query)
+		if got != tt.want || !errors.Is(err, tt.err) {
+			t.Errorf("%s: searchMatch(%q) = %s, %v, want %s, %v", tt.name, tt.query, got, err, tt.want, tt.err)
+		}
+	}
+}
@@ -4,18 +4,39 @@ import (
 	"forum/internal/repository"
 )
 
-// Service is a struct that implements the Authorization, PostItem and Comment interfaces.
+// Service is a struct that implements the Authorization, AccessToken, PostItem, CategoryItem, TagItem, AttachmentItem, Comment,
+// ReactionItem, Moderation and SearchIndex interfaces.
 type Service struct {
 	Authorization
+	AccessToken
 	PostItem
+	CategoryItem
+	TagItem
+	AttachmentItem
 	Comment
+	ReactionItem
+	Moderation
+	SearchIndex
+}
+
+// Config holds the settings of the services.
+type Config struct {
+	// Reactions are the emoji that users can react with besides liking and disliking.
+	Reactions []string
 }
 
 // NewService returns a new instance of Service.
-func NewService(repos *repository.Repository) *Service {
+func NewService(repos *repository.Repository, config Config) *Service {
 	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
-		PostItem:      NewPostService(repos.PostItem),
-		Comment:       NewCommentService(repos.Comment),
+		Authorization:  NewAuthService(repos.Authorization),
+		AccessToken:    NewAccessTokenService(repos.AccessToken),
+		PostItem:       NewPostService(repos.PostItem, repos.CategoryItem, repos.AttachmentItem),
+		CategoryItem:   NewCategoryService(repos.CategoryItem),
+		TagItem:        NewTagService(repos.TagItem),
+		AttachmentItem: NewAttachmentService(repos.AttachmentItem, repos.PostItem),
+		Comment:        NewCommentService(repos.Comment),
+		ReactionItem:   NewReactionService(repos.ReactionItem, config.Reactions),
+		Moderation:     NewModerationService(repos.Report, repos.PostItem, repos.Comment),
+		SearchIndex:    NewSearchService(repos.SearchIndex),
 	}
 }
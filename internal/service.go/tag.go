@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A custom error that is returned when a tag fails to meet validation criteria.
+var ErrInvalidTag = errors.New("invalid tag")
+
+const (
+	maxTagLength = 32
+	maxPostTags  = 5
+	// maxTagList is the most tags a tag listing returns.
+	maxTagList = 200
+)
+
+// An interface that defines methods for reading tags. It is implemented by the TagService struct.
+type TagItem interface {
+	GetTags(prefix string, limit int) ([]models.Tag, error)
+}
+
+type TagService struct {
+	repo repository.TagItem
+}
+
+// NewTagService returns a new instance of TagService.
+func NewTagService(repo repository.TagItem) *TagService {
+	return &TagService{repo: repo}
+}
+
+// GetTags returns the tags in use that start with prefix, the most used first.
+// The prefix is normalized like a tag, a limit out of range returns up to maxTagList tags.
+func (t *TagService) GetTags(prefix string, limit int) ([]models.Tag, error) {
+	if limit <= 0 || limit > maxTagList {
+		limit = maxTagList
+	}
+
+	prefix, _ = normalizeText(prefix, "")
+	tags, err := t.repo.ListTags(strings.Join(strings.Fields(strings.ToLower(prefix)), "-"), limit)
+	if err != nil {
+		return nil, fmt.Errorf("service: get tags: %w", err)
+	}
+	return tags, nil
+}
+
+// normalizeTags normalizes the tags picked for a post, values may hold several tags separated by commas.
+// The same tag picked twice counts once, and a post carries at most maxPostTags tags.
+func normalizeTags(values []string) ([]string, error) {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		for _, tag := range strings.Split(value, ",") {
+			tag, err := normalizeTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) > maxPostTags {
+		return nil, fmt.Errorf("more than %d tags: %w", maxPostTags, ErrInvalidTag)
+	}
+	return tags, nil
+}
+
+// normalizeTag puts a tag in NFC like the rest of the text, lowers its case and joins its words with hyphens,
+// so that "Web  Dev" becomes "web-dev". Tags are made of letters with their marks, digits and the characters
+// - _ . + #, an empty tag is returned as is.
+func normalizeTag(tag string) (string, error) {
+	tag, ok := normalizeText(tag, "")
+	if !ok {
+		return "", fmt.Errorf("tag %q contains unsupported characters: %w", tag, ErrInvalidTag)
+	}
+	tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+	if utf8.RuneCountInString(tag) > maxTagLength {
+		return "", fmt.Errorf("tag %q is longer than %d characters: %w", tag, maxTagLength, ErrInvalidTag)
+	}
+	for _, char := range tag {
+		if !unicode.IsLetter(char) && !unicode.IsMark(char) && !unicode.IsDigit(char) && !strings.ContainsRune("-_.+#", char) {
+			return "", fmt.Errorf("tag %q contains unsupported characters: %w", tag, ErrInvalidTag)
+		}
+	}
+	return tag, nil
+}
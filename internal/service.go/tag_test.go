@@ -0,0 +1,46 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   []string
+	}{
+		{"words", []string{"Web  Dev"}, []string{"web-dev"}},
+		{"commas", []string{"go, sql", "go"}, []string{"go", "sql"}},
+		{"composed and decomposed", []string{"caf\u00e9", "cafe\u0301", "CAF\u00c9"}, []string{"caf\u00e9"}},
+		{"marks without a composite", []string{"\u0939\u093f\u0902\u0926\u0940"}, []string{"\u0939\u093f\u0902\u0926\u0940"}},
+		{"symbols", []string{"c++", "c#", ".net"}, []string{"c++", "c#", ".net"}},
+		{"empty", []string{"", " , "}, nil},
+	}
+	for _, tt := range tests {
+		got, err := normalizeTags(tt.values)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: normalizeTags(%+q) = %+q, want %+q", tt.name, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTagsInvalid(t *testing.T) {
+	for _, values := range [][]string{
+		{"a/b"},
+		{"a\u202eb"},
+		{"a\x00b"},
+		{"a\xffb"},
+		{"abcdefghijklmnopqrstuvwxyz0123456"},
+		{"a", "b", "c", "d", "e", "f"},
+	} {
+		if _, err := normalizeTags(values); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("normalizeTags(%+q): err = %v, want ErrInvalidTag", values, err)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// normalizeText puts text in Unicode Normalization Form C, so that the same text is always stored the same way,
+// and reports whether every character of it may be stored. Text must be valid UTF-8 and must not hold control
+// characters other than the ones in allowed, nor the characters that override the direction of text.
+func normalizeText(value, allowed string) (string, bool) {
+	if !utf8.ValidString(value) {
+		return value, false
+	}
+	value = norm.NFC.String(value)
+	for _, char := range value {
+		if (unicode.IsControl(char) && !strings.ContainsRune(allowed, char)) || isBidiControl(char) {
+			return value, false
+		}
+	}
+	return value, true
+}
+
+// isBidiControl reports whether char embeds, overrides or isolates the direction of the text that follows it.
+// Such characters can make text read differently from what it holds, so they are never accepted.
+func isBidiControl(char rune) bool {
+	return (char >= 0x202a && char <= 0x202e) || (char >= 0x2066 && char <= 0x2069)
+}
+
+// isValidLength reports whether value holds from min to max characters, counted as runes rather than bytes.
+func isValidLength(value string, min, max int) bool {
+	length := utf8.RuneCountInString(value)
+	return length >= min && length <= max
+}
@@ -0,0 +1,31 @@
+package service
+
+import "testing"
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		allowed string
+		want    string
+		ok      bool
+	}{
+		{"ascii", "hello, world", "", "hello, world", true},
+		{"composed", "caf\u00e9", "", "caf\u00e9", true},
+		{"decomposed", "cafe\u0301", "", "caf\u00e9", true},
+		{"hangul jamo", "\u1100\u1161\u11a8", "", "\uac01", true},
+		{"emoji", "go \U0001f680", "", "go \U0001f680", true},
+		{"allowed tab", "a\tb", "\t", "a\tb", true},
+		{"tab", "a\tb", "", "a\tb", false},
+		{"nul", "a\x00b", "\t", "a\x00b", false},
+		{"right to left override", "a\u202eb", "", "a\u202eb", false},
+		{"isolate", "a\u2066b", "", "a\u2066b", false},
+		{"invalid utf-8", "a\xffb", "", "a\xffb", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeText(tt.value, tt.allowed)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: normalizeText(%+q, %q) = %+q, %v, want %+q, %v", tt.name, tt.value, tt.allowed, got, ok, tt.want, tt.ok)
+		}
+	}
+}
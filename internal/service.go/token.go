@@ -0,0 +1,149 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"forum/internal/models"
+	"forum/internal/repository"
+	"strings"
+	"time"
+)
+
+// Scopes limit what a request is allowed to do.
+// Session cookies carry every scope, access tokens only the ones chosen when they were created.
+const (
+	ScopeRead    = "read"
+	ScopePost    = "post"
+	ScopeComment = "comment"
+	// ScopeAccount covers managing sessions and access tokens. It is never granted to access tokens.
+	ScopeAccount = "account"
+)
+
+// accessTokenPrefix makes forum tokens easy to recognise, e.g. by secret scanners.
+const accessTokenPrefix = "forum_"
+
+var (
+	ErrInvalidTokenName    = errors.New("invalid token name")
+	ErrInvalidScope        = errors.New("invalid scope")
+	ErrAccessTokenNotFound = errors.New("access token not found")
+)
+
+// TokenScopes are the scopes that can be granted to an access token.
+var TokenScopes = []string{ScopeRead, ScopePost, ScopeComment}
+
+// SessionScopes are the scopes of a request authenticated with a session cookie.
+var SessionScopes = []string{ScopeRead, ScopePost, ScopeComment, ScopeAccount}
+
+// An interface that defines methods for managing personal access tokens.
+type AccessToken interface {
+	CreateAccessToken(userID int, name string, scopes []string) (models.AccessToken, error)
+	GetAccessTokens(userID int) ([]models.AccessToken, error)
+	GetUserByAccessToken(token string) (models.User, []string, error)
+	RevokeAccessToken(userID, tokenID int) error
+}
+
+// AccessTokenService is a struct that implements the AccessToken interface.
+type AccessTokenService struct {
+	repo repository.AccessToken
+}
+
+// NewAccessTokenService returns a new instance of AccessTokenService.
+func NewAccessTokenService(repo repository.AccessToken) *AccessTokenService {
+	return &AccessTokenService{repo: repo}
+}
+
+// CreateAccessToken generates a new access token for the user.
+// The returned token is the only place where its plain text value is available.
+func (s *AccessTokenService) CreateAccessToken(userID int, name string, scopes []string) (models.AccessToken, error) {
+	name, ok := normalizeText(strings.TrimSpace(name), "")
+	if !ok || !isValidLength(name, 1, 50) {
+		return models.AccessToken{}, ErrInvalidTokenName
+	}
+
+	if len(scopes) == 0 {
+		return models.AccessToken{}, ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !HasScope(TokenScopes, scope) {
+			return models.AccessToken{}, fmt.Errorf("service: create access token: %q: %w", scope, ErrInvalidScope)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return models.AccessToken{}, fmt.Errorf("service: create access token: %w", err)
+	}
+
+	token := models.AccessToken{
+		UserID:    userID,
+		Name:      name,
+		Token:     accessTokenPrefix + base64.RawURLEncoding.EncodeToString(raw),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	token.TokenHash = hashAccessToken(token.Token)
+
+	if err := s.repo.CreateAccessToken(&token); err != nil {
+		return models.AccessToken{}, fmt.Errorf("service: create access token: %w", err)
+	}
+
+	return token, nil
+}
+
+// GetAccessTokens returns the access tokens of a user without their plain text values.
+func (s *AccessTokenService) GetAccessTokens(userID int) ([]models.AccessToken, error) {
+	tokens, err := s.repo.GetAccessTokensByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("service: get access tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+// GetUserByAccessToken returns the owner of an access token and the scopes granted to it.
+func (s *AccessTokenService) GetUserByAccessToken(token string) (models.User, []string, error) {
+	if !strings.HasPrefix(token, accessTokenPrefix) {
+		return models.User{}, nil, ErrAccessTokenNotFound
+	}
+
+	user, accessToken, err := s.repo.GetUserByAccessTokenHash(hashAccessToken(token))
+	if err != nil {
+		return models.User{}, nil, fmt.Errorf("service: get user by access token: %w: %v", ErrAccessTokenNotFound, err)
+	}
+
+	if err = s.repo.UpdateAccessTokenLastUsed(accessToken.ID, time.Now()); err != nil {
+		return models.User{}, nil, fmt.Errorf("service: get user by access token: %w", err)
+	}
+
+	return user, accessToken.Scopes, nil
+}
+
+// RevokeAccessToken deletes an access token. Only tokens owned by the user can be revoked.
+func (s *AccessTokenService) RevokeAccessToken(userID, tokenID int) error {
+	ok, err := s.repo.DeleteAccessToken(userID, tokenID)
+	if err != nil {
+		return fmt.Errorf("service: revoke access token: %w", err)
+	}
+	if !ok {
+		return ErrAccessTokenNotFound
+	}
+	return nil
+}
+
+// HasScope reports whether scope is one of scopes.
+func HasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,56 @@
+// Package text holds skeletons, which tell names apart the way people read them.
+package text
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Skeleton returns what is left of a name once the differences that readers hardly notice are taken out,
+// so that names with the same skeleton can pass for each other: "alice", "Alice", "ａｌｉｃｅ", "a1ice"
+// and "аlice" with a Cyrillic а all have the same skeleton.
+//
+// The name is decomposed with compatibility mappings, which turns fullwidth, styled and ligature letters into
+// plain ones. Accents, format characters and spaces are dropped, Greek and Cyrillic letters that look like Latin
+// ones are replaced by them and case is ignored. Finally the lookalikes that only differ by a stroke or
+// spacing, like l, I and 1 or rn and m, are merged.
+func Skeleton(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(name) {
+		if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) || unicode.IsSpace(r) {
+			continue
+		}
+		if prototype, ok := prototypes[r]; ok {
+			r = prototype
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return lookalikes.Replace(b.String())
+}
+
+// prototypes maps letters of other scripts to the Latin letters that they look like.
+var prototypes = map[rune]rune{
+	// Greek
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M',
+	'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T', 'Υ': 'Y', 'Χ': 'X',
+	'α': 'a', 'γ': 'y', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u',
+	// Cyrillic
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P',
+	'С': 'C', 'Т': 'T', 'У': 'Y', 'Х': 'X', 'Ѕ': 'S', 'І': 'I', 'Ј': 'J', 'Ү': 'Y', 'Ӏ': 'l',
+	'а': 'a', 'е': 'e', 'о': 'o', 'р': 'p', 'с': 'c', 'у': 'y', 'х': 'x', 'ѕ': 's',
+	'і': 'i', 'ј': 'j', 'ԁ': 'd', 'һ': 'h', 'ԛ': 'q', 'ԝ': 'w', 'ү': 'y', 'ӏ': 'l', 'ь': 'b',
+	// Latin
+	'ı': 'i', 'ɩ': 'i', 'ȷ': 'j', 'ɡ': 'g', 'ǀ': 'l',
+}
+
+// lookalikes merges the lower case letters and digits that are told apart by little more than a stroke.
+var lookalikes = strings.NewReplacer(
+	"rn", "m",
+	"vv", "w",
+	"1", "l",
+	"i", "l",
+	"|", "l",
+	"0", "o",
+)
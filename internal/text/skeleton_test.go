@@ -0,0 +1,80 @@
+package text
+
+import "testing"
+
+func TestSkeleton(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "bob", "bob"},
+		{"upper case", "ALICE", "allce"},
+		{"fullwidth", "ＡＬＩＣＥ", "allce"},
+		{"mathematical bold", "\U0001d41a\U0001d425\U0001d422\U0001d41c\U0001d41e", "allce"},
+		{"circled", "ⓐlice", "allce"},
+		{"ligature", "ﬁona", "flona"},
+		{"digraph", "Ǆ", "dz"},
+		{"accent", "alíce", "allce"},
+		{"combining accent", "ali\u0301ce", "allce"},
+		{"zero width joiner", "ali\u200dce", "allce"},
+		{"bidi override", "x\u202ey", "xy"},
+		{"space", "a lice", "allce"},
+		{"cyrillic a", "\u0430lice", "allce"},
+		{"greek capital alpha", "\u0391lice", "allce"},
+		{"dotless i", "al\u0131ce", "allce"},
+		{"digit one", "a1ice", "allce"},
+		{"vertical bar", "al|ce", "allce"},
+		{"digit zero", "b0b", "bob"},
+		{"greek omicron", "b\u03bfb", "bob"},
+		{"cyrillic o", "b\u043eb", "bob"},
+		{"r and n", "rnary", "mary"},
+		{"two v", "vvill", "wlll"},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		if got := Skeleton(tt.in); got != tt.want {
+			t.Errorf("%s: Skeleton(%+q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+// TestSkeletonConfusable checks pairs of names that sign up has to refuse next to each other, and pairs that
+// are merely alike, which it has to let through.
+func TestSkeletonConfusable(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		confusing bool
+	}{
+		{"alice", "Alice", true},
+		{"alice", "\u0430lic\u0435", true},
+		{"alice", "ａｌｉｃｅ", true},
+		{"alice", "a1ice", true},
+		{"paypal", "\u0440\u0430\u0443\u0440\u0430l", true},
+		{"mary", "rnary", true},
+		{"will", "vvi11", true},
+		{"bob", "B0B", true},
+		{"jose", "jos\u00e9", true},
+		{"alice", "alicia", false},
+		{"bob", "rob", false},
+		{"mary", "marty", false},
+		{"anna", "ann", false},
+	}
+	for _, tt := range tests {
+		a, b := Skeleton(tt.a), Skeleton(tt.b)
+		if (a == b) != tt.confusing {
+			t.Errorf("Skeleton(%+q) = %q and Skeleton(%+q) = %q, confusing = %v, want %v", tt.a, a, tt.b, b, a == b, tt.confusing)
+		}
+	}
+}
+
+// TestSkeletonIdempotent checks that a skeleton is its own skeleton, so that storing it loses nothing that
+// a later lookup would need.
+func TestSkeletonIdempotent(t *testing.T) {
+	for _, name := range []string{"alice", "rnrn", "vvvv", "Il1|", "Ǆ", "ﬁona", "b\u043eb"} {
+		skeleton := Skeleton(name)
+		if again := Skeleton(skeleton); again != skeleton {
+			t.Errorf("Skeleton(%q) = %q, want %q", skeleton, again, skeleton)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Suggests existing tags while typing in a tag input. The input holds tags separated by commas,
+// the suggestions complete the last one and keep the tags typed before it.
+document.querySelectorAll("input[data-tag-input]").forEach((input) => {
+  const list = document.getElementById(input.getAttribute("list"));
+  let pending;
+
+  input.addEventListener("input", () => {
+    clearTimeout(pending);
+    pending = setTimeout(async () => {
+      const terms = input.value.split(",");
+      const prefix = terms.pop().trim();
+      list.innerHTML = "";
+      if (prefix === "") {
+        return;
+      }
+
+      const response = await fetch("/api/v1/tags?limit=10&prefix=" + encodeURIComponent(prefix));
+      if (!response.ok) {
+        return;
+      }
+      const { tags } = await response.json();
+      const typed = terms.map((term) => term.trim()).filter((term) => term !== "");
+      for (const tag of tags) {
+        const option = document.createElement("option");
+        option.value = typed.concat(tag.name).join(", ");
+        option.label = tag.name + " (" + tag.count + ")";
+        list.appendChild(option);
+      }
+    }, 150);
+  });
+});